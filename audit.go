@@ -0,0 +1,45 @@
+package gopq
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditEntry is one line written to Client.AuditWriter. The password is
+// never included and User is masked as in PrimusQuery.Redacted.
+type AuditEntry struct {
+	Time       time.Time `json:"time"`
+	Operation  string    `json:"operation"`
+	QueryID    string    `json:"queryId,omitempty"`
+	Host       string    `json:"host"`
+	User       string    `json:"user,omitempty"`
+	Database   string    `json:"database,omitempty"`
+	Search     string    `json:"search,omitempty"`
+	Loader     string    `json:"loader,omitempty"`
+	DurationMs int64     `json:"durationMs"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// audit writes entry as a JSON line to the client's AuditWriter, if any.
+func (c *Client) audit(entry AuditEntry, start time.Time, err error) {
+	if c.AuditWriter == nil {
+		return
+	}
+	entry.Time = start.UTC()
+	entry.DurationMs = time.Since(start).Milliseconds()
+	entry.Success = err == nil
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	line, marshalErr := json.Marshal(entry)
+	if marshalErr != nil {
+		c.Logger.Errorf("cannot encode audit entry: %s", marshalErr)
+		return
+	}
+	c.auditMu.Lock()
+	defer c.auditMu.Unlock()
+	if _, writeErr := c.AuditWriter.Write(append(line, '\n')); writeErr != nil {
+		c.Logger.Errorf("cannot write audit entry: %s", writeErr)
+	}
+}
@@ -0,0 +1,90 @@
+package gopq
+
+import (
+	"context"
+	"sync"
+)
+
+type BatchResult struct {
+	Index  int
+	Output string
+	Err    error
+}
+
+// ExecuteBatch runs the queries sequentially and keeps going when a query
+// fails. The results are in the same order as the queries.
+func (c *Client) ExecuteBatch(ctx context.Context, queries []PrimusQuery, timeout int) []BatchResult {
+	return c.executeBatch(ctx, queries, timeout, false)
+}
+
+// ExecuteBatchFailFast runs the queries sequentially and stops at the first
+// failing query, so the last result holds the error.
+func (c *Client) ExecuteBatchFailFast(ctx context.Context, queries []PrimusQuery, timeout int) []BatchResult {
+	return c.executeBatch(ctx, queries, timeout, true)
+}
+
+func (c *Client) executeBatch(ctx context.Context, queries []PrimusQuery, timeout int, failFast bool) []BatchResult {
+	results := make([]BatchResult, 0, len(queries))
+	for i, query := range queries {
+		result := BatchResult{Index: i}
+		if err := ctx.Err(); err != nil {
+			result.Err = err
+		} else {
+			result.Output, result.Err = c.ExecuteAndRead(ctx, query, timeout)
+		}
+		results = append(results, result)
+		if result.Err != nil && failFast {
+			break
+		}
+	}
+	return results
+}
+
+// ExecuteParallel runs the queries with up to concurrency workers. Output
+// and error for each query are stored at the query's index. Queries not yet
+// started when ctx is done get the context error.
+func (c *Client) ExecuteParallel(ctx context.Context, queries []PrimusQuery, timeout, concurrency int) ([]string, []error) {
+	outputs := make([]string, len(queries))
+	errs := make([]error, len(queries))
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > len(queries) {
+		concurrency = len(queries)
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if err := ctx.Err(); err != nil {
+					errs[i] = err
+					continue
+				}
+				outputs[i], errs[i] = c.ExecuteAndRead(ctx, queries[i], timeout)
+			}
+		}()
+	}
+	for i := range queries {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return outputs, errs
+}
+
+func ExecuteBatch(ctx context.Context, queries []PrimusQuery, timeout int) []BatchResult {
+	return defaultClient().ExecuteBatch(ctx, queries, timeout)
+}
+
+func ExecuteBatchFailFast(ctx context.Context, queries []PrimusQuery, timeout int) []BatchResult {
+	return defaultClient().ExecuteBatchFailFast(ctx, queries, timeout)
+}
+
+func ExecuteParallel(ctx context.Context, queries []PrimusQuery, timeout, concurrency int) ([]string, []error) {
+	return defaultClient().ExecuteParallel(ctx, queries, timeout, concurrency)
+}
@@ -0,0 +1,99 @@
+package gopq
+
+import "strings"
+
+// QueryBuilder builds a PrimusQuery with chained calls. Build validates the
+// result.
+type QueryBuilder struct {
+	query PrimusQuery
+	err   error
+}
+
+func NewQueryBuilder() *QueryBuilder {
+	return &QueryBuilder{}
+}
+
+func (b *QueryBuilder) WithHost(h string) *QueryBuilder {
+	b.query.Host = h
+	return b
+}
+
+func (b *QueryBuilder) WithPort(p string) *QueryBuilder {
+	b.query.Port = p
+	return b
+}
+
+func (b *QueryBuilder) WithCredentials(user, pass string) *QueryBuilder {
+	b.query.User = user
+	b.query.Pass = pass
+	return b
+}
+
+func (b *QueryBuilder) WithCharset(c Charset) *QueryBuilder {
+	b.query.Charset = c
+	return b
+}
+
+func (b *QueryBuilder) WithDatabase(d string) *QueryBuilder {
+	b.query.Database = d
+	return b
+}
+
+func (b *QueryBuilder) WithSearch(s string) *QueryBuilder {
+	b.query.Search = s
+	return b
+}
+
+// WithConditions sets the search from key=value conditions, see
+// BuildSearch. An invalid operator is reported by Build.
+func (b *QueryBuilder) WithConditions(conditions map[string]string, operator string) *QueryBuilder {
+	search, err := BuildSearch(conditions, operator)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	b.query.Search = search
+	return b
+}
+
+func (b *QueryBuilder) WithSort(fields ...string) *QueryBuilder {
+	b.query.Sort = strings.Join(fields, " ")
+	return b
+}
+
+func (b *QueryBuilder) WithOutput(o string) *QueryBuilder {
+	b.query.Output = o
+	return b
+}
+
+func (b *QueryBuilder) WithHeader(h string) *QueryBuilder {
+	b.query.Header = h
+	return b
+}
+
+func (b *QueryBuilder) WithData(d string) *QueryBuilder {
+	b.query.Data = d
+	return b
+}
+
+func (b *QueryBuilder) WithFooter(f string) *QueryBuilder {
+	b.query.Footer = f
+	return b
+}
+
+// WithLimit returns at most limit records after skipping offset records.
+func (b *QueryBuilder) WithLimit(limit, offset int) *QueryBuilder {
+	b.query.Limit = limit
+	b.query.Offset = offset
+	return b
+}
+
+func (b *QueryBuilder) Build() (PrimusQuery, error) {
+	if b.err != nil {
+		return PrimusQuery{}, b.err
+	}
+	if err := b.query.Validate(); err != nil {
+		return PrimusQuery{}, err
+	}
+	return b.query, nil
+}
@@ -0,0 +1,94 @@
+package gopq
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+type cacheEntry struct {
+	result  ExecutionResult
+	expires time.Time
+}
+
+type forceRefreshKey struct{}
+
+// WithForceRefresh returns a context that makes ExecuteAndRead skip and
+// replace a cached result.
+func WithForceRefresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forceRefreshKey{}, true)
+}
+
+func forceRefresh(ctx context.Context) bool {
+	force, _ := ctx.Value(forceRefreshKey{}).(bool)
+	return force
+}
+
+// cachedResult returns the cached result for the query if there is one
+// that has not expired. Expired entries are removed.
+func (c *Client) cachedResult(ctx context.Context, key string) (ExecutionResult, bool) {
+	if !c.CacheResults {
+		return ExecutionResult{}, false
+	}
+	if forceRefresh(ctx) {
+		c.cache.Delete(key)
+		return ExecutionResult{}, false
+	}
+	value, ok := c.cache.Load(key)
+	if !ok {
+		return ExecutionResult{}, false
+	}
+	entry := value.(cacheEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		c.cache.Delete(key)
+		return ExecutionResult{}, false
+	}
+	atomic.AddUint64(&c.counters.cacheHits, 1)
+	return entry.result, true
+}
+
+func (c *Client) storeResult(key string, result ExecutionResult) {
+	if !c.CacheResults {
+		return
+	}
+	entry := cacheEntry{result: result}
+	if c.CacheTTL > 0 {
+		entry.expires = time.Now().Add(c.CacheTTL)
+		c.sweepCache()
+	}
+	c.cache.Store(key, entry)
+}
+
+// sweepCache removes the expired entries, at most once per CacheTTL, so
+// entries that are never looked up again do not pile up.
+func (c *Client) sweepCache() {
+	now := time.Now()
+	c.sweepMu.Lock()
+	if now.Sub(c.lastSweep) < c.CacheTTL {
+		c.sweepMu.Unlock()
+		return
+	}
+	c.lastSweep = now
+	c.sweepMu.Unlock()
+
+	c.cache.Range(func(key, value interface{}) bool {
+		if expires := value.(cacheEntry).expires; !expires.IsZero() && now.After(expires) {
+			c.cache.Delete(key)
+		}
+		return true
+	})
+}
+
+// cacheKey is the query's Fingerprint combined with a hash of its
+// credentials, so a cached result is only served to callers that ran the
+// query as the same user with the same password.
+func cacheKey(query PrimusQuery) string {
+	h := sha256.New()
+	for _, field := range []string{query.Fingerprint(), query.User, query.Pass} {
+		fmt.Fprintf(h, "%d:%s;", len(field), field)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,115 @@
+package gopq
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newCachingClient(ttl time.Duration) (*Client, *MockExecutor) {
+	mock := NewMockExecutor()
+	mock.SetResponse(testQuery().Search, MockResponse{Output: "first"})
+	c := NewClient("primusquery", WithExecutor(mock))
+	c.CacheResults = true
+	c.CacheTTL = ttl
+	return c, mock
+}
+
+func readOutput(t *testing.T, c *Client, ctx context.Context, query PrimusQuery) string {
+	t.Helper()
+	output, err := c.ExecuteAndRead(ctx, query, 0)
+	if err != nil {
+		t.Fatalf("ExecuteAndRead: %v", err)
+	}
+	return output
+}
+
+func TestCacheHit(t *testing.T) {
+	c, mock := newCachingClient(0)
+	query := testQuery()
+	readOutput(t, c, context.Background(), query)
+	mock.SetResponse(query.Search, MockResponse{Output: "second"})
+
+	if got := readOutput(t, c, context.Background(), query); got != "first" {
+		t.Errorf("cached output = %q, want %q", got, "first")
+	}
+	if n := len(mock.Calls()); n != 1 {
+		t.Errorf("executor called %d times, want 1", n)
+	}
+	if hits := c.Metrics().CacheHits; hits != 1 {
+		t.Errorf("CacheHits = %d, want 1", hits)
+	}
+}
+
+func TestCacheSeparatesCredentials(t *testing.T) {
+	c, mock := newCachingClient(0)
+	query := testQuery()
+	readOutput(t, c, context.Background(), query)
+	mock.SetResponse(query.Search, MockResponse{Output: "second"})
+
+	tests := []struct {
+		name  string
+		query PrimusQuery
+	}{
+		{"other user", query.WithCredentials("other", query.Pass)},
+		{"other password", query.WithCredentials(query.User, "other-secret")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := readOutput(t, c, context.Background(), tt.query); got != "second" {
+				t.Errorf("output = %q, want %q from a new run", got, "second")
+			}
+		})
+	}
+	if n := len(mock.Calls()); n != 3 {
+		t.Errorf("executor called %d times, want 3", n)
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	c, mock := newCachingClient(20 * time.Millisecond)
+	query := testQuery()
+	readOutput(t, c, context.Background(), query)
+	mock.SetResponse(query.Search, MockResponse{Output: "second"})
+	time.Sleep(40 * time.Millisecond)
+
+	if got := readOutput(t, c, context.Background(), query); got != "second" {
+		t.Errorf("output after expiry = %q, want %q", got, "second")
+	}
+	if n := len(mock.Calls()); n != 2 {
+		t.Errorf("executor called %d times, want 2", n)
+	}
+}
+
+func TestCacheSweepsExpiredEntries(t *testing.T) {
+	c, _ := newCachingClient(20 * time.Millisecond)
+	readOutput(t, c, context.Background(), testQuery())
+	time.Sleep(40 * time.Millisecond)
+	readOutput(t, c, context.Background(), testQuery().Clone(func(q *PrimusQuery) { q.Search = "V1=Jones" }))
+
+	entries := 0
+	c.cache.Range(func(key, value interface{}) bool {
+		entries++
+		return true
+	})
+	if entries != 1 {
+		t.Errorf("cache has %d entries, want the expired one swept", entries)
+	}
+}
+
+func TestCacheForceRefresh(t *testing.T) {
+	c, mock := newCachingClient(0)
+	query := testQuery()
+	readOutput(t, c, context.Background(), query)
+	mock.SetResponse(query.Search, MockResponse{Output: "second"})
+
+	if got := readOutput(t, c, WithForceRefresh(context.Background()), query); got != "second" {
+		t.Errorf("refreshed output = %q, want %q", got, "second")
+	}
+	if got := readOutput(t, c, context.Background(), query); got != "second" {
+		t.Errorf("output after refresh = %q, want the refreshed %q", got, "second")
+	}
+	if n := len(mock.Calls()); n != 2 {
+		t.Errorf("executor called %d times, want 2", n)
+	}
+}
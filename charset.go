@@ -0,0 +1,39 @@
+package gopq
+
+import "strings"
+
+// Charset is a character set name understood by primusquery.
+type Charset string
+
+const (
+	CharsetUTF8        Charset = "UTF-8"
+	CharsetUTF8Primus  Charset = "UTF8" // spelling used in Primus configuration
+	CharsetLatin1      Charset = "LATIN1"
+	CharsetISO88591    Charset = "ISO-8859-1"
+	CharsetISO88592    Charset = "ISO-8859-2"
+	CharsetISO885915   Charset = "ISO-8859-15"
+	CharsetWindows1252 Charset = "WINDOWS-1252"
+)
+
+// ValidCharsets returns the charsets known to be accepted by primusquery.
+func ValidCharsets() []Charset {
+	return []Charset{
+		CharsetUTF8,
+		CharsetUTF8Primus,
+		CharsetLatin1,
+		CharsetISO88591,
+		CharsetISO88592,
+		CharsetISO885915,
+		CharsetWindows1252,
+	}
+}
+
+// IsValid reports whether c is one of ValidCharsets, ignoring case.
+func (c Charset) IsValid() bool {
+	for _, valid := range ValidCharsets() {
+		if strings.EqualFold(string(c), string(valid)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,219 @@
+package gopq
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+type Client struct {
+	counters clientCounters
+
+	PrimusQueryPath string
+	Debug           bool
+	Logger          Logger
+	RetryPolicy     RetryPolicy
+
+	// AutoUpdate runs UpdatePQ against the query host before the first
+	// Execute or ExecuteAndRead.
+	AutoUpdate bool
+	// UpdateTimeout limits UpdatePQ and ForceUpdatePQ without a timeout of
+	// their own, DefaultUpdateTimeout when zero.
+	UpdateTimeout time.Duration
+	// TempDir is where query files are created, the OS default when empty.
+	TempDir string
+	// SecureQueryFile creates query files in the memory backed /dev/shm
+	// on Linux, so the credentials in them never reach the disk. TempDir
+	// is used on other platforms or when /dev/shm cannot be written.
+	SecureQueryFile bool
+	// FallbackHosts are tried in order when the query host is unreachable.
+	FallbackHosts []string
+	// SafeDeletePasses is the number of times query and import files are
+	// overwritten before removal, DefaultSafeDeletePasses when zero.
+	SafeDeletePasses int
+	// OnBeforeExecute and OnAfterExecute are called around every query
+	// run, also when it fails. Output is empty for streamed queries.
+	OnBeforeExecute func(query PrimusQuery)
+	OnAfterExecute  func(query PrimusQuery, output string, duration time.Duration, err error)
+
+	// TempFileNamer, when set, adds a random part to temp file names after
+	// the "gopq-" prefix. Names are unique without it; see CryptoRandomNamer.
+	TempFileNamer func(length int) string
+	// AuditWriter receives one JSON encoded AuditEntry line per query and
+	// import run when set.
+	AuditWriter io.Writer
+	// MaxConcurrent limits the number of primusquery processes run at the
+	// same time, unlimited when zero. It must be set before first use.
+	MaxConcurrent int
+	// MaxQueueDepth is the number of callers allowed to wait for a
+	// MaxConcurrent slot; more get ErrQueueFull. Unlimited when zero.
+	MaxQueueDepth int
+	// QueriesPerSecond throttles query runs to this rate, unlimited when
+	// zero or negative. Callers block until their query may start.
+	QueriesPerSecond float64
+	// CacheResults makes ExecuteAndRead keep results in memory by query
+	// Fingerprint and credentials for CacheTTL, or until WithForceRefresh
+	// when zero.
+	CacheResults bool
+	CacheTTL     time.Duration
+	// AllowPartialOutput makes ExecuteAndRead return the output written
+	// before primusquery exited non-zero together with the *PrimusError.
+	AllowPartialOutput bool
+	// SanitizeOutput makes ExecuteAndRead clean its output with the
+	// SanitizeOutput function. NewClient sets it.
+	SanitizeOutput bool
+	// MaxOutputLines makes ExecuteAndRead return ErrTooManyResults instead
+	// of output with more lines, unlimited when zero. The output is still
+	// read before it is counted; use Limit to have Primus return less.
+	MaxOutputLines int
+	// DrainTimeout limits how long Close waits for running queries before
+	// cancelling them. Close waits for them to finish when zero.
+	DrainTimeout time.Duration
+	// DebugQueryPath is where a redacted copy of each query file is
+	// written when Debug is set. A "%s" in it is replaced with the query's
+	// QueryID or a timestamp so concurrent queries do not overwrite each
+	// other; empty writes no debug file.
+	DebugQueryPath string
+
+	executor   Executor
+	tracer     Tracer
+	updated    bool
+	stateMu    sync.RWMutex
+	updateOnce sync.Once
+	updateLock chan struct{}
+	auditMu    sync.Mutex
+	semOnce    sync.Once
+	sem        chan struct{}
+	queued     int32
+	limiter    rateLimiter
+	cache      sync.Map
+	sweepMu    sync.Mutex
+	lastSweep  time.Time
+
+	inflight  sync.WaitGroup
+	closed    bool
+	abort     chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// IsUpdated reports whether primusquery -update has been run by this
+// client.
+func (c *Client) IsUpdated() bool {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
+	return c.updated
+}
+
+// SetUpdated marks the client as updated or not. Setting it to false makes
+// the next UpdatePQ run the update again.
+func (c *Client) SetUpdated(v bool) {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	c.updated = v
+}
+
+// Reset returns the client's state to that of a new client with the same
+// configuration: the updated flag is cleared, cached results are dropped and
+// the rate limiter starts with a full bucket and the Metrics counters are
+// zeroed. Queries in flight are not affected.
+func (c *Client) Reset() {
+	c.SetUpdated(false)
+	c.cache.Range(func(key, _ interface{}) bool {
+		c.cache.Delete(key)
+		return true
+	})
+	c.limiter.reset()
+	c.resetCounters()
+}
+
+// Option configures a Client created with NewClient.
+type Option func(*Client)
+
+func WithLogger(l Logger) Option {
+	return func(c *Client) {
+		if l != nil {
+			c.Logger = l
+		}
+	}
+}
+
+func NewClient(binaryPath string, opts ...Option) *Client {
+	if binaryPath == "" {
+		binaryPath = "./primusquery"
+	}
+	c := &Client{
+		PrimusQueryPath:  binaryPath,
+		Logger:           noopLogger{},
+		SafeDeletePasses: DefaultSafeDeletePasses,
+		DebugQueryPath:   DefaultDebugQueryPath,
+		SanitizeOutput:   true,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+var (
+	std = &Client{
+		Logger:           NewStdLogger(log.New(os.Stderr, "", log.LstdFlags)),
+		SafeDeletePasses: DefaultSafeDeletePasses,
+		DebugQueryPath:   DefaultDebugQueryPath,
+		SanitizeOutput:   true,
+	}
+	stdMu sync.Mutex
+)
+
+// defaultClient returns the client behind the package-level functions,
+// synced with the package-level Debug and PrimusQueryPath settings.
+func defaultClient() *Client {
+	stdMu.Lock()
+	defer stdMu.Unlock()
+	if std.PrimusQueryPath != PrimusQueryPath {
+		std.PrimusQueryPath = PrimusQueryPath
+	}
+	if std.Debug != Debug {
+		std.Debug = Debug
+	}
+	return std
+}
+
+// SetTempDir sets the directory used for query files by the package-level
+// functions.
+func SetTempDir(dir string) {
+	stdMu.Lock()
+	defer stdMu.Unlock()
+	std.TempDir = dir
+}
+
+func UpdatePQ(host string, port string) error {
+	return defaultClient().UpdatePQ(host, port)
+}
+
+func ForceUpdatePQ(ctx context.Context, host string, port string, timeout int) error {
+	return defaultClient().ForceUpdatePQ(ctx, host, port, timeout)
+}
+
+func ExecuteImportQuery(ctx context.Context, filename string, primusHost, primusPort, userName string, password string, loaderName string) (string, error) {
+	return defaultClient().ExecuteImportQuery(ctx, filename, primusHost, primusPort, userName, password, loaderName)
+}
+
+func ExecuteImportQueryCapture(ctx context.Context, filename string, primusHost, primusPort, userName string, password string, loaderName string) (ExecutionResult, error) {
+	return defaultClient().ExecuteImportQueryCapture(ctx, filename, primusHost, primusPort, userName, password, loaderName)
+}
+
+func ExecuteAtomicImportQuery(ctx context.Context, filename string, primusHost, primusPort, userName string, password string, loaderName string, opts ...ImportOption) (ImportResult, error) {
+	return defaultClient().ExecuteAtomicImportQuery(ctx, filename, primusHost, primusPort, userName, password, loaderName, opts...)
+}
+
+func ExecuteAndRead(ctx context.Context, query PrimusQuery, timeout int) (string, error) {
+	return defaultClient().ExecuteAndRead(ctx, query, timeout)
+}
+
+func Execute(ctx context.Context, query PrimusQuery, timeout int) error {
+	return defaultClient().Execute(ctx, query, timeout)
+}
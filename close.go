@@ -0,0 +1,85 @@
+package gopq
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// begin registers a query or import run with the client so Close can wait
+// for it. The returned context is cancelled when Close gives up waiting;
+// the returned function must be called when the run is done.
+func (c *Client) begin(ctx context.Context) (context.Context, func(), error) {
+	c.stateMu.Lock()
+	if c.closed {
+		c.stateMu.Unlock()
+		return nil, nil, ErrClientClosed
+	}
+	if c.abort == nil {
+		c.abort = make(chan struct{})
+	}
+	abort := c.abort
+	c.inflight.Add(1)
+	c.stateMu.Unlock()
+	atomic.AddUint64(&c.counters.inFlight, 1)
+
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-abort:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		cancel()
+		atomic.AddUint64(&c.counters.inFlight, ^uint64(0))
+		c.inflight.Done()
+	}, nil
+}
+
+// Close stops the client from starting new queries and waits for running
+// ones to finish, for at most DrainTimeout when it is set. Queries still
+// running then are cancelled and an error is returned. The AuditWriter is
+// flushed if it has a Flush method. Calling Close again returns the result
+// of the first call.
+func (c *Client) Close() error {
+	c.closeOnce.Do(func() {
+		c.stateMu.Lock()
+		c.closed = true
+		if c.abort == nil {
+			c.abort = make(chan struct{})
+		}
+		c.stateMu.Unlock()
+
+		drained := make(chan struct{})
+		go func() {
+			c.inflight.Wait()
+			close(drained)
+		}()
+		var timeout <-chan time.Time
+		if c.DrainTimeout > 0 {
+			timer := time.NewTimer(c.DrainTimeout)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		select {
+		case <-drained:
+		case <-timeout:
+			close(c.abort)
+			<-drained
+			c.closeErr = fmt.Errorf("close: queries still running after %s: %w", c.DrainTimeout, context.DeadlineExceeded)
+		}
+
+		if flusher, ok := c.AuditWriter.(interface{ Flush() error }); ok {
+			c.auditMu.Lock()
+			err := flusher.Flush()
+			c.auditMu.Unlock()
+			if err != nil && c.closeErr == nil {
+				c.closeErr = fmt.Errorf("close: flush audit writer: %w", err)
+			}
+		}
+	})
+	return c.closeErr
+}
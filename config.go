@@ -0,0 +1,73 @@
+package gopq
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Config holds the settings of a Client for NewClientFromConfig. Zero
+// values get the same defaults as NewClient. Logger and AuditWriter cannot
+// be decoded and have to be set in code.
+type Config struct {
+	PrimusQueryPath  string        `json:"primusQueryPath,omitempty"`
+	TempDir          string        `json:"tempDir,omitempty"`
+	MaxConcurrent    int           `json:"maxConcurrent,omitempty"`
+	QueriesPerSecond float64       `json:"queriesPerSecond,omitempty"`
+	CacheResults     bool          `json:"cacheResults,omitempty"`
+	CacheTTL         time.Duration `json:"cacheTTL,omitempty"`
+	UpdateTimeout    time.Duration `json:"updateTimeout,omitempty"`
+	SafeDeletePasses int           `json:"safeDeletePasses,omitempty"`
+	AuditWriter      io.Writer     `json:"-"`
+	Logger           Logger        `json:"-"`
+}
+
+// NewClientFromConfig checks cfg and returns a client configured with it.
+// An error wrapping ErrInvalidConfig is returned for negative limits or a
+// TempDir that is not a directory.
+func NewClientFromConfig(cfg Config) (*Client, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	c := NewClient(cfg.PrimusQueryPath, WithLogger(cfg.Logger))
+	c.TempDir = cfg.TempDir
+	c.MaxConcurrent = cfg.MaxConcurrent
+	c.QueriesPerSecond = cfg.QueriesPerSecond
+	c.CacheResults = cfg.CacheResults
+	c.CacheTTL = cfg.CacheTTL
+	c.UpdateTimeout = cfg.UpdateTimeout
+	if cfg.SafeDeletePasses > 0 {
+		c.SafeDeletePasses = cfg.SafeDeletePasses
+	}
+	c.AuditWriter = cfg.AuditWriter
+	return c, nil
+}
+
+func (cfg Config) validate() error {
+	negative := []struct {
+		name     string
+		negative bool
+	}{
+		{"MaxConcurrent", cfg.MaxConcurrent < 0},
+		{"QueriesPerSecond", cfg.QueriesPerSecond < 0},
+		{"CacheTTL", cfg.CacheTTL < 0},
+		{"UpdateTimeout", cfg.UpdateTimeout < 0},
+		{"SafeDeletePasses", cfg.SafeDeletePasses < 0},
+	}
+	for _, field := range negative {
+		if field.negative {
+			return fmt.Errorf("%w: %s is negative", ErrInvalidConfig, field.name)
+		}
+	}
+	if cfg.TempDir != "" {
+		info, err := os.Stat(cfg.TempDir)
+		if err != nil {
+			return fmt.Errorf("%w: TempDir: %v", ErrInvalidConfig, err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%w: TempDir %s is not a directory", ErrInvalidConfig, cfg.TempDir)
+		}
+	}
+	return nil
+}
@@ -1,16 +1,19 @@
 package gopq
 
 import (
+	"bytes"
 	"context"
+	cryptorand "crypto/rand"
 	"errors"
-	"io/ioutil"
-	"log"
+	"fmt"
+	"io"
 	"math/rand"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -20,11 +23,27 @@ const charset = "abcdefghijklmnopqrstuvwxyz" +
 var (
 	seededRand *rand.Rand = rand.New(
 		rand.NewSource(time.Now().UnixNano()))
+	seededRandMu    sync.Mutex
 	Debug           = false
 	PrimusQueryPath = "./primusquery"
+	// DefaultTimeout is the query timeout in seconds used when neither the
+	// timeout argument nor PrimusQuery.TimeoutSeconds is set.
+	DefaultTimeout = 60
+	// DefaultUpdateTimeout is the primusquery -update timeout used when
+	// Client.UpdateTimeout is not set.
+	DefaultUpdateTimeout = 60 * time.Second
 )
 
+// DefaultSafeDeletePasses is the number of overwrite passes used when
+// Client.SafeDeletePasses is not set.
+const DefaultSafeDeletePasses = 10
+
+// DefaultDebugQueryPath is the Client.DebugQueryPath set by NewClient.
+const DefaultDebugQueryPath = "debug.priq"
+
 func StringWithCharset(length int) string {
+	seededRandMu.Lock()
+	defer seededRandMu.Unlock()
 	b := make([]byte, length)
 	for i := range b {
 		b[i] = charset[seededRand.Intn(len(charset))]
@@ -32,41 +51,121 @@ func StringWithCharset(length int) string {
 	return string(b)
 }
 
-func createFile(filename string, content string) error {
-	err := ioutil.WriteFile(filename, []byte(content), 0644)
+// CryptoRandomNamer returns a random string of the given length from the
+// same characters as StringWithCharset, using crypto/rand. It can be set as
+// Client.TempFileNamer.
+func CryptoRandomNamer(length int) string {
+	// bytes above the largest multiple of len(charset) are skipped to
+	// keep the distribution uniform
+	limit := 256 - 256%len(charset)
+	b := make([]byte, 0, length)
+	random := make([]byte, length)
+	for len(b) < length {
+		if _, err := cryptorand.Read(random); err != nil {
+			panic("gopq: crypto/rand failed: " + err.Error())
+		}
+		for _, r := range random {
+			if int(r) < limit && len(b) < length {
+				b = append(b, charset[int(r)%len(charset)])
+			}
+		}
+	}
+	return string(b)
+}
+
+// tempFilePattern returns the os.CreateTemp pattern for temp files with
+// the given extension. os.CreateTemp makes the names unique; TempFileNamer,
+// when set, adds a random part of its own.
+func (c *Client) tempFilePattern(ext string) string {
+	if c.TempFileNamer != nil {
+		return "gopq-" + c.TempFileNamer(16) + "-*" + ext
+	}
+	return "gopq-*" + ext
+}
+
+// debugQueryPath returns DebugQueryPath with a "%s" replaced by the query
+// ID, or the current time when there is none. Without a "%s" the query ID
+// is added before the extension.
+func (c *Client) debugQueryPath(queryID string) string {
+	if !strings.Contains(c.DebugQueryPath, "%s") {
+		if queryID == "" {
+			return c.DebugQueryPath
+		}
+		ext := filepath.Ext(c.DebugQueryPath)
+		return strings.TrimSuffix(c.DebugQueryPath, ext) + "-" + queryID + ext
+	}
+	id := queryID
+	if id == "" {
+		id = time.Now().Format("20060102T150405.000000000")
+	}
+	return strings.Replace(c.DebugQueryPath, "%s", id, 1)
+}
+
+// createFile creates filename with mode 0600 and writes content to it. It
+// fails if the file already exists, so a symlink planted at the path is
+// never followed.
+func (c *Client) createFile(filename string, content string) error {
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err == nil {
+		_, err = file.WriteString(content)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
 	if err != nil {
-		if Debug {
-			log.Printf("creating the file failed: %s", err)
+		if c.Debug {
+			c.Logger.Debugf("creating the file failed: %s", err)
 		}
-		return err
+		return fileError("create file", filename, err)
 	}
 	return nil
 }
 
 func CreateTMPFile(filename string, content string) (string, error) {
-	tmpfile, err := ioutil.TempFile("", filename)
+	return defaultClient().createTMPFile(filename, content)
+}
+
+func (c *Client) createTMPFile(pattern string, content string) (string, error) {
+	tmpfile, err := c.createTempFile(pattern, content)
 	if err != nil {
-		if Debug {
-			log.Printf("creating tmp-file failed")
-		}
 		return "", err
 	}
-	_, err = tmpfile.WriteString(content)
+	err = tmpfile.Close()
 	if err != nil {
-		_ = tmpfile.Close()
-		if Debug {
-			log.Printf("writing on the tmp-file failed: %s", err)
+		_ = os.Remove(tmpfile.Name())
+		if c.Debug {
+			c.Logger.Debugf("closing the tmp-file failed: %s", err)
 		}
-		return "", err
+		return "", fileError("close tmp-file", tmpfile.Name(), err)
 	}
-	err = tmpfile.Close()
+	return tmpfile.Name(), nil
+}
+
+// createTempFile creates a temp file in TempDir from pattern and writes
+// content to it. The file is returned open; on error nothing is left behind.
+func (c *Client) createTempFile(pattern string, content string) (*os.File, error) {
+	return c.createTempFileIn(c.TempDir, pattern, content)
+}
+
+// createTempFileIn is createTempFile with the directory given.
+func (c *Client) createTempFileIn(dir string, pattern string, content string) (*os.File, error) {
+	tmpfile, err := os.CreateTemp(dir, pattern)
 	if err != nil {
-		if Debug {
-			log.Printf("closing the tmp-file failed")
+		if c.Debug {
+			c.Logger.Debugf("creating tmp-file failed: %s", err)
 		}
-		return "", err
+		return nil, fileError("create tmp-file", dir, err)
 	}
-	return tmpfile.Name(), nil
+	_, err = tmpfile.WriteString(content)
+	if err != nil {
+		_ = tmpfile.Close()
+		_ = os.Remove(tmpfile.Name())
+		if c.Debug {
+			c.Logger.Debugf("writing on the tmp-file failed: %s", err)
+		}
+		return nil, fileError("write tmp-file", tmpfile.Name(), err)
+	}
+	return tmpfile, nil
 }
 
 func FileExists(filename string) bool {
@@ -78,274 +177,635 @@ func FileExists(filename string) bool {
 }
 
 func SafeDelete(filename string) error {
+	return defaultClient().safeDelete(filename)
+}
+
+func (c *Client) safeDelete(filename string) error {
 	file, err := os.OpenFile(filename, os.O_RDWR, 0666)
 
 	if err != nil {
-		if Debug {
-			log.Printf("cannot open file %s: %s", filename, err)
+		if c.Debug {
+			c.Logger.Debugf("cannot open file %s: %s", filename, err)
 		}
 		return err
 	}
 	defer file.Close()
 
-	fileInfo, err := file.Stat()
-	if err != nil {
-		log.Fatalf("cannot read file: %s", filename)
+	if err := c.overwrite(file); err != nil {
 		return err
 	}
 
-	var size int64 = fileInfo.Size()
-	zeroBytes := make([]byte, size)
-
-	for i := 0; i < 10; i++ {
-		copy(zeroBytes[:], strconv.Itoa(i))
-		_, err := file.Write([]byte(zeroBytes))
-		if err != nil {
-			log.Printf("cannot write on file: %s", filename)
-			return err
-		}
-	}
-
 	err = file.Close()
 	if err != nil {
-		log.Fatalf("cannot close file: %s", filename)
-		return err
+		c.Logger.Errorf("cannot close file: %s", filename)
+		return fileError("safe delete", filename, err)
 	}
 
 	err = os.Remove(filename)
 	if err != nil {
-		log.Fatalf("cannot remove file: %s", filename)
-		return err
+		c.Logger.Errorf("cannot remove file: %s", filename)
+		return fileError("safe delete", filename, err)
 	}
 
 	return nil
 }
 
+// overwrite writes over the whole content of file SafeDeletePasses times
+// with the PatternDoDM sequence.
+func (c *Client) overwrite(file *os.File) error {
+	passes := c.SafeDeletePasses
+	if passes <= 0 {
+		passes = DefaultSafeDeletePasses
+	}
+	if err := shred(file, passes, PatternDoDM); err != nil {
+		c.Logger.Errorf("cannot overwrite file: %s", file.Name())
+		return err
+	}
+	return nil
+}
+
 func RemoveFile(filename string) error {
+	return defaultClient().removeFile(filename)
+}
+
+func (c *Client) removeFile(filename string) error {
 	err := os.Remove(filename)
 
 	if err != nil {
-		if Debug {
-			log.Printf("removing file %s failed: %s", filename, err)
+		if c.Debug {
+			c.Logger.Debugf("removing file %s failed: %s", filename, err)
 		}
 		return err
 	}
 	return nil
 }
 
-func SetQuery(query PrimusQuery) string {
-	queryString := "#CHARSET " + query.Charset + "\n"
-	queryString = queryString + "#HOST " + query.Host + "\n"
-	queryString = queryString + "#PORT " + query.Port + "\n"
-	queryString = queryString + "#USER " + query.User + "\n"
-	queryString = queryString + "#PASS " + query.Pass + "\n"
-	queryString = queryString + "#OUTPUT " + query.Output + "\n"
-	queryString = queryString + "#DATABASE " + query.Database + "\n"
-	queryString = queryString + "#SEARCH " + query.Search + "\n"
-	queryString = queryString + "#SORT " + "V1" + "\n"
-	if query.Header != "" {
-		queryString = queryString + "#HEADER_START\n" + query.Header + "\n#HEADER_STOP\n"
-	}
-	queryString = queryString + query.Data + "\n"
-	if query.Footer != "" {
-		queryString = queryString + "#FOOTER_START\n" + query.Footer + "\n#FOOTER_STOP\n"
+// QueryText returns the query in the primusquery query file format.
+func (q PrimusQuery) QueryText() string {
+	var sb strings.Builder
+	return SetQueryBuilder(&q, &sb)
+}
+
+// SetQueryBuilder appends the query file text of q to sb and returns the
+// text appended. Reusing one builder, e.g. one per worker, saves the
+// allocations QueryText makes for each query.
+func SetQueryBuilder(q *PrimusQuery, sb *strings.Builder) string {
+	start := sb.Len()
+	writeQueryText(sb, q)
+	return sb.String()[start:]
+}
+
+// SetQueryBytes returns the query file text of query as bytes, for writing
+// to a file without a string copy.
+func SetQueryBytes(query PrimusQuery) []byte {
+	var buf bytes.Buffer
+	writeQueryText(&buf, &query)
+	return buf.Bytes()
+}
+
+// queryTextWriter is implemented by both strings.Builder and bytes.Buffer.
+type queryTextWriter interface {
+	io.StringWriter
+	io.ByteWriter
+	Grow(n int)
+}
+
+// writeQueryText writes the query file text of q to sb, growing it once to
+// the size estimated from the field lengths.
+func writeQueryText(sb queryTextWriter, q *PrimusQuery) {
+	sb.Grow(len(q.Host) + len(q.User) + len(q.Pass) + len(q.Output) + len(q.Database) +
+		len(q.Search) + len(q.Sort) + len(q.Header) + len(q.Data) + len(q.Footer) + 160)
+	line := func(directive, value string) {
+		sb.WriteString(directive)
+		sb.WriteByte(' ')
+		sb.WriteString(value)
+		sb.WriteByte('\n')
+	}
+	line("#CHARSET", string(q.Charset))
+	line("#HOST", q.Host)
+	line("#PORT", q.Port)
+	line("#USER", q.User)
+	line("#PASS", q.Pass)
+	if q.Output != "" {
+		line("#OUTPUT", q.Output)
+	}
+	line("#DATABASE", q.Database)
+	line("#SEARCH", q.Search)
+	sort := "V1"
+	if strings.TrimSpace(q.Sort) != "" {
+		sort = strings.Join(strings.Fields(q.Sort), " ")
+	}
+	line("#SORT", sort)
+	if q.Limit > 0 {
+		line("#LIMIT", strconv.Itoa(q.Limit))
+	}
+	if q.Offset > 0 {
+		line("#OFFSET", strconv.Itoa(q.Offset))
+	}
+	if q.Header != "" {
+		sb.WriteString("#HEADER_START\n")
+		sb.WriteString(q.Header)
+		sb.WriteString("\n#HEADER_STOP\n")
+	}
+	sb.WriteString(q.Data)
+	sb.WriteByte('\n')
+	if q.Footer != "" {
+		sb.WriteString("#FOOTER_START\n")
+		sb.WriteString(q.Footer)
+		sb.WriteString("\n#FOOTER_STOP\n")
 	}
-	return queryString
 }
 
+// SetQuery returns the query file text of query.
+//
+// Deprecated: Use PrimusQuery.QueryText.
+func SetQuery(query PrimusQuery) string {
+	return query.QueryText()
+}
+
+// RepairPrimusGeneratedJSON repairs the JSON file f in place with RepairJSON.
+// Valid JSON is written back unchanged.
 func RepairPrimusGeneratedJSON(f string) error {
-	// TODO: more complicated JSON-arrays
-	jsonAsBytes, err := ioutil.ReadFile(f)
+	return defaultClient().repairPrimusGeneratedJSON(f)
+}
+
+func (c *Client) repairPrimusGeneratedJSON(f string) error {
+	in, err := os.Open(f)
 	if err != nil {
-		log.Fatalf("cannot read %s JSON-file: %s", f, err)
-		return err
+		c.Logger.Errorf("cannot read %s JSON-file: %s", f, err)
+		return fileError("repair JSON", f, err)
 	}
-
-	jsonAsString := string(jsonAsBytes)
-	if strings.Contains(jsonAsString, ",") {
-		err := SafeDelete(f)
-		if err != nil {
-			return err
-		}
-
-		time.Sleep(2 * time.Second)
-		end := len(jsonAsString) - 6
-		repairedJSON := jsonAsString[0:end] + "\n]"
-		err = createFile(f, repairedJSON)
-		if err != nil {
-			return err
+	var repaired bytes.Buffer
+	err = RepairJSON(in, &repaired)
+	in.Close()
+	if err != nil {
+		if c.Debug {
+			c.Logger.Debugf("repairing %s JSON-file failed: %s", f, err)
 		}
+		return fmt.Errorf("repair JSON %s: %w", f, err)
 	}
 
+	out, err := os.Create(f)
+	if err != nil {
+		return fileError("repair JSON", f, err)
+	}
+	_, err = out.Write(repaired.Bytes())
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fileError("repair JSON", f, err)
+	}
 	return nil
 }
 
+// CountPQErrors sums the counts of every "Errors: N" line in the output.
 func CountPQErrors(output string) (int, error) {
-	errorsPattern := regexp.MustCompile(`Errors: ([0-9])+`)
-	matches := errorsPattern.Find([]byte(output))
-	if len(matches) >= 1 {
-		errorCountPattern := regexp.MustCompile(`([0-9])+`)
-		numbers := string(errorCountPattern.Find(matches))
-		count, err := strconv.Atoi(numbers)
+	count, err := sumMatches(errorCountPattern, output)
+	if err != nil {
+		return -1, err
+	}
+	return count, nil
+}
+
+// CountPQWarnings sums the counts of every "Warnings: N" line in the output.
+func CountPQWarnings(output string) (int, error) {
+	count, err := sumMatches(warningCountPattern, output)
+	if err != nil {
+		return -1, err
+	}
+	return count, nil
+}
+
+var newCardPattern = regexp.MustCompile(`NEW: ([0-9]+)`)
+
+// ParseNewCardIDs returns the IDs of every "NEW: N" line in the output, or
+// nil when there is none.
+func ParseNewCardIDs(output string) ([]int, error) {
+	var cardIDs []int
+	for _, match := range newCardPattern.FindAllStringSubmatch(output, -1) {
+		cardID, err := strconv.Atoi(match[1])
 		if err != nil {
-			return -1, err
+			return nil, err
 		}
-		return count, err
+		cardIDs = append(cardIDs, cardID)
 	}
-	return 0, nil
+	return cardIDs, nil
 }
 
+// NewCardIDs returns the IDs of every "NEW: N" line in the output.
+//
+// Deprecated: Use ParseNewCardIDs.
+func NewCardIDs(output string) ([]int, error) {
+	return ParseNewCardIDs(output)
+}
+
+// NewCardID returns the first new card ID, or -1 when there is none.
+//
+// Deprecated: Use ParseNewCardIDs.
 func NewCardID(output string) (int, error) {
-	newCardPattern := regexp.MustCompile(`NEW: ([0-9])+`)
-	founded := newCardPattern.Find([]byte(output))
-	if len(founded) >= 1 {
-		cardIDPattern := regexp.MustCompile(`([0-9])+`)
-		number := string(cardIDPattern.Find(founded))
-		cardID, err := strconv.Atoi(number)
-		if err != nil {
-			return -1, err
-		}
-		return cardID, nil
+	cardIDs, err := ParseNewCardIDs(output)
+	if err != nil {
+		return -1, err
+	}
+	if len(cardIDs) == 0 {
+		return -1, nil
 	}
-	return -1, nil
+	return cardIDs[0], nil
 }
 
-func UpdatePQ(host string, port string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+// UpdatePQ runs primusquery -update unless the client is already updated.
+// Concurrent callers wait for a running update instead of starting their own.
+func (c *Client) UpdatePQ(host string, port string) error {
+	return c.updatePQ(context.Background(), host, port)
+}
+
+// updatePQ is UpdatePQ bounded by ctx, both while waiting for a running
+// update and while updating.
+func (c *Client) updatePQ(ctx context.Context, host string, port string) error {
+	unlock, err := c.lockUpdate(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if c.IsUpdated() {
+		return nil
+	}
+	return c.update(ctx, host, port, c.updateTimeout())
+}
+
+// ForceUpdatePQ runs primusquery -update even when the client is already
+// updated, e.g. after the Primus server has been restarted. A timeout of
+// zero uses the client's UpdateTimeout, or DefaultUpdateTimeout when that is
+// not set.
+func (c *Client) ForceUpdatePQ(ctx context.Context, host string, port string, timeout int) error {
+	unlock, err := c.lockUpdate(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	c.SetUpdated(false)
+	d := c.updateTimeout()
+	if timeout > 0 {
+		d = time.Duration(timeout) * time.Second
+	}
+	return c.update(ctx, host, port, d)
+}
+
+// lockUpdate waits until no other update is running, or until ctx is done.
+func (c *Client) lockUpdate(ctx context.Context) (func(), error) {
+	c.updateOnce.Do(func() {
+		c.updateLock = make(chan struct{}, 1)
+	})
+	select {
+	case c.updateLock <- struct{}{}:
+		return func() { <-c.updateLock }, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("update: %w", ctx.Err())
+	}
+}
+
+// updateTimeout returns the client's UpdateTimeout, DefaultUpdateTimeout
+// when it is not set.
+func (c *Client) updateTimeout() time.Duration {
+	if c.UpdateTimeout > 0 {
+		return c.UpdateTimeout
+	}
+	return DefaultUpdateTimeout
+}
+
+func (c *Client) update(ctx context.Context, host string, port string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
-	cmd := exec.CommandContext(ctx, PrimusQueryPath, host, port, "-update")
-	out, err := cmd.Output()
-	if ctx.Err() == context.DeadlineExceeded {
-		return errors.New("primusquery update timeout")
+	var out bytes.Buffer
+	stderr, err := c.exec().Update(ctx, c.PrimusQueryPath, host, port, &out)
+	if ctx.Err() != nil {
+		return newContextError("update", ErrUpdateTimeout, ctx.Err())
 	}
 	if err != nil {
-		if Debug {
-			log.Printf("PQ update fails: %s", err)
+		if c.Debug {
+			c.Logger.Debugf("PQ update fails: %s", err)
 		}
-		return err
+		pe := newPrimusError("update", err)
+		pe.Stderr = stderr
+		return pe
 	}
-	if Debug {
-		log.Printf("update output: %s", out)
+	if c.Debug {
+		c.Logger.Debugf("update output: %s", out.String())
 	}
+	c.SetUpdated(true)
 
 	return nil
 }
 
-func ExecuteImportQuery(filename string, primusHost, primusPort, userName string, password string, loaderName string) (string, error) {
+// ExecuteImportQuery runs an import with the given import file and removes
+// the file afterwards. The output is returned also when the import fails,
+// possibly cut short.
+func (c *Client) ExecuteImportQuery(ctx context.Context, filename string, primusHost, primusPort, userName string, password string, loaderName string) (string, error) {
+	result, err := c.ExecuteImportQueryCapture(ctx, filename, primusHost, primusPort, userName, password, loaderName)
+	return result.Output, err
+}
+
+// ExecuteImportQueryCapture works like ExecuteImportQuery and also returns
+// what primusquery wrote to stderr.
+func (c *Client) ExecuteImportQueryCapture(ctx context.Context, filename string, primusHost, primusPort, userName string, password string, loaderName string) (ExecutionResult, error) {
 	if FileExists(filename) {
-		output, err := exec.Command(PrimusQueryPath, primusHost, primusPort, userName, password, loaderName, "-i", filename).Output()
+		ctx, done, err := c.begin(ctx)
+		if err != nil {
+			return ExecutionResult{}, fmt.Errorf("import query %s: %w", loaderName, err)
+		}
+		defer done()
+		release, err := c.acquire(ctx)
 		if err != nil {
-			if Debug {
-				log.Printf("import query %s failed: %s", loaderName, err)
-			} else {
-				_ = SafeDelete(filename)
+			return ExecutionResult{}, fmt.Errorf("import query %s: %w", loaderName, err)
+		}
+		defer release()
+
+		start := time.Now()
+		var stdout bytes.Buffer
+		req := ImportRequest{
+			Host:     primusHost,
+			Port:     primusPort,
+			User:     userName,
+			Password: password,
+			Loader:   loaderName,
+			File:     filename,
+		}
+		stderr, err := c.exec().ExecuteImportQuery(ctx, c.PrimusQueryPath, req, &stdout)
+		if ctx.Err() != nil {
+			err = newContextError("import query "+loaderName, ErrQueryTimeout, ctx.Err())
+		} else if err != nil {
+			pe := newPrimusError("import query "+loaderName, err)
+			pe.Stderr = stderr
+			err = pe
+		}
+		if stderr != "" && c.Debug {
+			c.Logger.Debugf("import query %s stderr: %s", loaderName, stderr)
+		}
+		c.countResult(err)
+		c.audit(AuditEntry{Operation: "import query", Host: primusHost, User: maskUser(userName), Loader: loaderName}, start, err)
+		if err != nil {
+			if c.Debug {
+				c.Logger.Debugf("import query %s failed: %s", loaderName, err)
+			} else if FileExists(filename) {
+				_ = c.safeDelete(filename)
 			}
-			return "", err
-		} else if len(output) > 0 && Debug {
-			log.Printf("import query %s output: %s", loaderName, output)
+			// the output primusquery produced before failing helps to
+			// debug the import
+			return ExecutionResult{Output: stdout.String(), Stderr: stderr, Host: primusHost}, err
+		} else if stdout.Len() > 0 && c.Debug {
+			c.Logger.Debugf("import query %s output: %s", loaderName, stdout.String())
 		}
-		_ = SafeDelete(filename)
-		return string(output), err
+		_ = c.safeDelete(filename)
+		return ExecutionResult{Output: stdout.String(), Stderr: stderr, Host: primusHost}, nil
 	} else {
-		if Debug {
-			log.Printf("%s import-file %s not exists", loaderName, filename)
+		if c.Debug {
+			c.Logger.Debugf("%s import-file %s not exists", loaderName, filename)
 		}
-		return "", errors.New("import-file not exists")
+		return ExecutionResult{}, fmt.Errorf("%s: %w", filename, ErrImportFileNotFound)
 	}
 }
 
-func ExecuteAtomicImportQuery(filename string, primusHost, primusPort, userName string, password string, loaderName string) (int, int, error) {
+// ExecuteAtomicImportQuery imports one card and returns its new card ID,
+// import error count and warnings. An ImportResult with the raw output is
+// returned also when the import fails. With WithRollbackPath a failed
+// import's file is kept for inspection and an *ImportError is returned.
+func (c *Client) ExecuteAtomicImportQuery(ctx context.Context, filename string, primusHost, primusPort, userName string, password string, loaderName string, opts ...ImportOption) (ImportResult, error) {
 	// todo: check import-file content and validity, one card element
-	output, err := ExecuteImportQuery(filename, primusHost, primusPort, userName, password, loaderName)
-	var (
-		newCardID  int
-		errorCount int
-	)
-	if err == nil {
-		newCardID, err = NewCardID(output)
+	var options importOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	var importData []byte
+	if options.rollbackPath != "" && FileExists(filename) {
+		data, err := os.ReadFile(filename)
 		if err != nil {
-			if Debug {
-				log.Printf("executing atomic import query %s failed: %s", loaderName, err)
-			}
-			return -1, -1, err
+			return ImportResult{NewCardID: -1}, fileError("read import-file", filename, err)
 		}
-		errorCount, err = CountPQErrors(output)
-		if err != nil {
-			if Debug {
-				log.Printf("executing atomic import query %s failed: %s", loaderName, err)
-			}
-			return -1, -1, err
+		importData = data
+	}
+
+	output, err := c.ExecuteImportQuery(ctx, filename, primusHost, primusPort, userName, password, loaderName)
+	result := ImportResult{NewCardID: -1, RawOutput: output}
+	if err != nil {
+		return result, err
+	}
+	cardIDs, err := ParseNewCardIDs(output)
+	if err != nil {
+		if c.Debug {
+			c.Logger.Debugf("executing atomic import query %s failed: %s", loaderName, err)
 		}
+		return result, err
 	}
-	return newCardID, errorCount, nil
+	if len(cardIDs) > 0 {
+		result.NewCardID = cardIDs[0]
+	}
+	result.ErrorCount, err = CountPQErrors(output)
+	if err != nil {
+		if c.Debug {
+			c.Logger.Debugf("executing atomic import query %s failed: %s", loaderName, err)
+		}
+		return result, err
+	}
+	result.Warnings = importWarnings(output)
+	if result.ErrorCount > 0 && options.rollbackPath != "" {
+		return result, c.rollbackImport(filename, importData, options.rollbackPath, result.ErrorCount)
+	}
+	return result, nil
 }
 
-func ExecuteAndRead(query PrimusQuery, timeout int) (string, error) {
+func (c *Client) ExecuteAndRead(ctx context.Context, query PrimusQuery, timeout int) (string, error) {
+	ctx, span := c.startSpan(ctx, "gopq.ExecuteAndRead", query)
+	result, err := c.ExecuteAndCapture(ctx, query, timeout)
+	span.End(err)
+	return result.Output, err
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
-	defer cancel()
-	query.Output = ""
-	queryText := SetQuery(query)
+func (c *Client) executeAndReadWithRetry(ctx context.Context, query PrimusQuery, timeout int) (ExecutionResult, error) {
+	var result ExecutionResult
+	err := c.withRetry(ctx, func() error {
+		var err error
+		result, err = c.executeAndRead(ctx, query, timeout)
+		return err
+	})
+	return result, err
+}
 
-	queryFilename := StringWithCharset(128)
-	queryFilename, err := CreateTMPFile(queryFilename, queryText)
+func (c *Client) executeAndRead(ctx context.Context, query PrimusQuery, timeout int) (ExecutionResult, error) {
+	if err := c.prepare(ctx, query); err != nil {
+		return ExecutionResult{}, err
+	}
+	query.Output = ""
+	var out bytes.Buffer
+	stderr, err := c.runQuery(ctx, "execute and read", query, timeout, &out)
 	if err != nil {
-		return "", err
+		result := ExecutionResult{Stderr: stderr, Host: query.Host}
+		var pe *PrimusError
+		if c.AllowPartialOutput && errors.As(err, &pe) {
+			result.Output = c.sanitize(out.String())
+		}
+		return result, err
 	}
-	if Debug {
-		_ = createFile("debug.priq", queryText)
+	if c.Debug {
+		c.Logger.Debugf("execute output: %s", out.String())
 	}
-
-	cmd := exec.CommandContext(ctx, PrimusQueryPath, queryFilename)
-	out, err := cmd.Output()
-	if ctx.Err() == context.DeadlineExceeded {
-		if Debug {
-			log.Printf("primus connection timeout: %s", err)
+	if c.MaxOutputLines > 0 {
+		if n := CountQueryOutputLines(out.String()); n > c.MaxOutputLines {
+			return ExecutionResult{Stderr: stderr, Host: query.Host}, fmt.Errorf("execute and read: %w: %d lines, limit %d", ErrTooManyResults, n, c.MaxOutputLines)
 		}
-		SafeDelete(queryFilename)
-		return "", err
 	}
+	return ExecutionResult{Output: c.sanitize(out.String()), Stderr: stderr, Host: query.Host}, nil
+}
 
-	err = SafeDelete(queryFilename)
-	if err != nil {
-		return string(out), err
+func (c *Client) Execute(ctx context.Context, query PrimusQuery, timeout int) error {
+	ctx, span := c.startSpan(ctx, "gopq.Execute", query)
+	_, err := c.withFailover(ctx, query, func(q PrimusQuery) error {
+		return c.withRetry(ctx, func() error {
+			return c.execute(ctx, q, timeout)
+		})
+	})
+	span.End(err)
+	return err
+}
+
+func (c *Client) execute(ctx context.Context, query PrimusQuery, timeout int) error {
+	if err := c.prepare(ctx, query); err != nil {
+		return err
 	}
+	_, err := c.runQuery(ctx, "execute", query, timeout, nil)
+	return err
+}
 
-	if Debug {
-		log.Printf("execute output: %s", string(out[:]))
+// sanitize applies SanitizeOutput when the client's SanitizeOutput is set.
+func (c *Client) sanitize(output string) string {
+	if c.SanitizeOutput {
+		return SanitizeOutput(output)
 	}
-	return string(out), nil
+	return output
 }
 
-func Execute(query PrimusQuery, timeout int) error {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
-	defer cancel()
-	queryText := SetQuery(query)
-	queryFilename := StringWithCharset(128)
-	queryFilename, err := CreateTMPFile(queryFilename, queryText)
-	if err != nil {
+// prepare validates the query and runs the automatic update if enabled,
+// bounded by ctx.
+func (c *Client) prepare(ctx context.Context, query PrimusQuery) error {
+	if err := query.Validate(); err != nil {
 		return err
 	}
-	if Debug {
-		_ = createFile("debug.priq", queryText)
+	if c.AutoUpdate {
+		if err := c.updatePQ(ctx, query.Host, query.Port); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	cmd := exec.CommandContext(ctx, PrimusQueryPath, queryFilename)
-	out, err := cmd.Output()
-	if ctx.Err() == context.DeadlineExceeded {
-		if Debug {
-			log.Printf("primus connection timeout: %s", err)
-		}
-		SafeDelete(queryFilename)
-		return err
+// queryTimeout picks the timeout argument when set, then the query's own
+// TimeoutSeconds and finally DefaultTimeout.
+func queryTimeout(query PrimusQuery, timeout int) time.Duration {
+	switch {
+	case timeout > 0:
+		return time.Duration(timeout) * time.Second
+	case query.TimeoutSeconds > 0:
+		return time.Duration(query.TimeoutSeconds) * time.Second
+	default:
+		return time.Duration(DefaultTimeout) * time.Second
 	}
+}
 
-	err = SafeDelete(queryFilename)
+// runQuery writes the query file, runs primusquery on it with stdout going
+// to the given writer and removes the file again. It returns what
+// primusquery wrote to stderr.
+func (c *Client) runQuery(ctx context.Context, op string, query PrimusQuery, timeout int, stdout io.Writer) (stderrOutput string, err error) {
+	ctx, done, err := c.begin(ctx)
 	if err != nil {
-		return err
+		return "", fmt.Errorf("%s: %w", op, err)
 	}
-	if Debug {
-		log.Printf("execute output: %s", string(out[:]))
+	defer done()
+	if err := c.limiter.wait(ctx, c.QueriesPerSecond); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
 	}
-	return nil
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	defer release()
+
+	start := time.Now()
+	if c.Debug && query.QueryID != "" {
+		c.Logger.Debugf("%s %s: started", op, query.QueryID)
+		defer func() {
+			c.Logger.Debugf("%s %s: finished in %s, err: %v", op, query.QueryID, time.Since(start), err)
+		}()
+	}
+	defer func() {
+		c.countResult(err)
+		redacted := query.Redacted()
+		c.audit(AuditEntry{Operation: op, QueryID: query.QueryID, Host: redacted.Host, User: redacted.User, Database: redacted.Database, Search: redacted.Search}, start, err)
+	}()
+	if c.OnBeforeExecute != nil {
+		c.OnBeforeExecute(query)
+	}
+	if c.OnAfterExecute != nil {
+		defer func() {
+			output := ""
+			if buf, ok := stdout.(*bytes.Buffer); ok {
+				output = buf.String()
+			}
+			c.OnAfterExecute(query, output, time.Since(start), err)
+		}()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout(query, timeout))
+	defer cancel()
+	queryFile := c.NewQueryFile()
+	if err := queryFile.Write(query); err != nil {
+		return "", err
+	}
+	// the query file holds the credentials, so it is removed also when
+	// the executor panics; the panic then wins over the delete error, and
+	// err is set so the outer defers record the run as failed
+	defer func() {
+		deleteErr := queryFile.SafeDelete()
+		if p := recover(); p != nil {
+			if deleteErr != nil {
+				c.Logger.Errorf("%s: cannot delete query file after panic: %s", op, deleteErr)
+			}
+			err = fmt.Errorf("%s: panic: %v", op, p)
+			panic(p)
+		}
+		if deleteErr != nil && err == nil {
+			err = deleteErr
+		}
+	}()
+	if c.Debug && c.DebugQueryPath != "" {
+		debugPath := c.debugQueryPath(query.QueryID)
+		// the previous copy, or anything planted in its place, is removed
+		// rather than written through
+		_ = os.Remove(debugPath)
+		_ = c.createFile(debugPath, query.Redacted().QueryText())
+	}
+
+	var stderr string
+	if stdout == nil {
+		stderr, err = c.exec().Execute(ctx, c.PrimusQueryPath, queryFile.Path())
+	} else {
+		stderr, err = c.exec().ExecuteAndRead(ctx, c.PrimusQueryPath, queryFile.Path(), stdout)
+	}
+	if stderr != "" && c.Debug {
+		c.Logger.Debugf("%s stderr: %s", op, stderr)
+	}
+	if ctx.Err() != nil {
+		if c.Debug {
+			c.Logger.Debugf("primus connection timeout: %s", err)
+		}
+		return stderr, newContextError(op, ErrQueryTimeout, ctx.Err())
+	}
+	if err != nil {
+		if c.Debug {
+			c.Logger.Debugf("%s failed: %s", op, err)
+		}
+		pe := newPrimusError(op, err)
+		pe.Stderr = stderr
+		return stderr, pe
+	}
+	return stderr, nil
 }
@@ -0,0 +1,149 @@
+package gopq
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueryTextOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{"empty", "", ""},
+		{"set", "/tmp/out.json", "#OUTPUT /tmp/out.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := testQuery()
+			q.Output = tt.output
+			var got []string
+			for _, line := range strings.Split(q.QueryText(), "\n") {
+				if directiveName(line) == "#OUTPUT" {
+					got = append(got, line)
+				}
+			}
+			switch {
+			case tt.want == "" && len(got) != 0:
+				t.Errorf("QueryText() has %q, want no #OUTPUT line", got)
+			case tt.want != "" && (len(got) != 1 || got[0] != tt.want):
+				t.Errorf("QueryText() #OUTPUT lines = %q, want [%q]", got, tt.want)
+			}
+		})
+	}
+}
+
+// concatQueryText builds the query file text with + as SetQuery did before
+// SetQueryBuilder, for comparison in the benchmarks.
+func concatQueryText(query PrimusQuery) string {
+	queryString := "#CHARSET " + string(query.Charset) + "\n"
+	queryString = queryString + "#HOST " + query.Host + "\n"
+	queryString = queryString + "#PORT " + query.Port + "\n"
+	queryString = queryString + "#USER " + query.User + "\n"
+	queryString = queryString + "#PASS " + query.Pass + "\n"
+	queryString = queryString + "#DATABASE " + query.Database + "\n"
+	queryString = queryString + "#SEARCH " + query.Search + "\n"
+	queryString = queryString + "#SORT " + query.Sort + "\n"
+	if query.Header != "" {
+		queryString = queryString + "#HEADER_START\n" + query.Header + "\n#HEADER_STOP\n"
+	}
+	queryString = queryString + query.Data + "\n"
+	if query.Footer != "" {
+		queryString = queryString + "#FOOTER_START\n" + query.Footer + "\n#FOOTER_STOP\n"
+	}
+	return queryString
+}
+
+func benchmarkQuery() PrimusQuery {
+	q := testQuery()
+	q.SetHeaderLines([]string{"[", "{"})
+	q.SetDataLines([]string{`"name": V1,`, `"first": V2,`, `"email": V3`})
+	q.SetFooterLines([]string{"}", "]"})
+	return q
+}
+
+func BenchmarkConcatQueryText(b *testing.B) {
+	q := benchmarkQuery()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = concatQueryText(q)
+	}
+}
+
+func BenchmarkQueryText(b *testing.B) {
+	q := benchmarkQuery()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = q.QueryText()
+	}
+}
+
+func BenchmarkSetQueryBuilder(b *testing.B) {
+	q := benchmarkQuery()
+	var sb strings.Builder
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		sb.Reset()
+		_ = SetQueryBuilder(&q, &sb)
+	}
+}
+
+func BenchmarkSetQueryBytes(b *testing.B) {
+	q := benchmarkQuery()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = SetQueryBytes(q)
+	}
+}
+
+// panicExecutor panics in Execute after recording the query file path.
+type panicExecutor struct {
+	*MockExecutor
+	queryFile string
+}
+
+func (p *panicExecutor) Execute(ctx context.Context, binaryPath, queryFile string) (string, error) {
+	p.queryFile = queryFile
+	panic("executor failed")
+}
+
+func TestRunQueryPanic(t *testing.T) {
+	executor := &panicExecutor{MockExecutor: NewMockExecutor()}
+	var audit bytes.Buffer
+	var afterErr error
+	c := NewClient("primusquery", WithExecutor(executor))
+	c.AuditWriter = &audit
+	c.OnAfterExecute = func(query PrimusQuery, output string, duration time.Duration, err error) {
+		afterErr = err
+	}
+
+	func() {
+		defer func() {
+			if p := recover(); p != "executor failed" {
+				t.Errorf("recovered %v, want the executor panic", p)
+			}
+		}()
+		_ = c.Execute(context.Background(), testQuery(), 0)
+	}()
+
+	if executor.queryFile == "" {
+		t.Fatal("executor was not called")
+	}
+	if _, err := os.Stat(executor.queryFile); !os.IsNotExist(err) {
+		t.Errorf("query file %s still exists: %v", executor.queryFile, err)
+	}
+	if m := c.Metrics(); m.Succeeded != 0 || m.Failed != 1 {
+		t.Errorf("Metrics() = %+v, want one failed run", m)
+	}
+	if afterErr == nil {
+		t.Error("OnAfterExecute got a nil error")
+	}
+	if !strings.Contains(audit.String(), `"success":false`) {
+		t.Errorf("audit entry %q is not a failure", audit.String())
+	}
+}
@@ -0,0 +1,63 @@
+package gopq
+
+import (
+	"os"
+	"strings"
+)
+
+// MissingEnvError lists every required environment variable that was not
+// set.
+type MissingEnvError struct {
+	Vars []string
+}
+
+func (e *MissingEnvError) Error() string {
+	return "missing environment variables: " + strings.Join(e.Vars, ", ")
+}
+
+func (e *MissingEnvError) Is(target error) bool {
+	return target == ErrInvalidQuery
+}
+
+// NewQueryFromEnv reads the connection fields of a query from the PRIMUS_*
+// environment variables. PRIMUS_CHARSET is optional and defaults to
+// CharsetUTF8. Search and the other query fields are left for the caller.
+func NewQueryFromEnv() (PrimusQuery, error) {
+	var (
+		query   PrimusQuery
+		charset string
+		missing []string
+	)
+	fields := []struct {
+		name     string
+		value    *string
+		required bool
+	}{
+		{"PRIMUS_HOST", &query.Host, true},
+		{"PRIMUS_PORT", &query.Port, true},
+		{"PRIMUS_USER", &query.User, true},
+		{"PRIMUS_PASS", &query.Pass, true},
+		{"PRIMUS_DATABASE", &query.Database, true},
+		{"PRIMUS_CHARSET", &charset, false},
+	}
+	for _, field := range fields {
+		value, ok := os.LookupEnv(field.name)
+		if (!ok || value == "") && field.required {
+			missing = append(missing, field.name)
+			continue
+		}
+		*field.value = value
+	}
+	if len(missing) > 0 {
+		return PrimusQuery{}, &MissingEnvError{Vars: missing}
+	}
+	query.Charset = Charset(charset)
+	if query.Charset == "" {
+		query.Charset = CharsetUTF8
+	}
+
+	if err := query.validateConnection(); err != nil {
+		return PrimusQuery{}, err
+	}
+	return query, nil
+}
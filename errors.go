@@ -0,0 +1,180 @@
+package gopq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+var (
+	ErrImportFileNotFound = errors.New("import-file not exists")
+	ErrUpdateTimeout      = errors.New("primusquery update timeout")
+	ErrQueryTimeout       = errors.New("primusquery query timeout")
+	ErrInvalidQuery       = errors.New("invalid primus query")
+	ErrUnrepairableJSON   = errors.New("primus generated JSON cannot be repaired")
+	ErrUnreachable        = errors.New("primus server unreachable")
+	ErrBinaryNotFound     = errors.New("primusquery binary not found")
+	ErrQueueFull          = errors.New("primusquery queue full")
+	ErrClientClosed       = errors.New("gopq client closed")
+	ErrInvalidConfig      = errors.New("invalid gopq config")
+	ErrTooManyResults     = errors.New("primusquery output exceeds MaxOutputLines")
+)
+
+// PrimusError is returned when the primusquery binary fails to run or
+// exits with a non-zero code.
+type PrimusError struct {
+	Op       string
+	ExitCode int
+	Stderr   string
+	Err      error
+}
+
+func (e *PrimusError) Error() string {
+	msg := fmt.Sprintf("%s: primusquery failed: %s", e.Op, e.Err)
+	if e.ExitCode >= 0 {
+		msg = fmt.Sprintf("%s: primusquery exited with code %d", e.Op, e.ExitCode)
+	}
+	if stderr := strings.TrimSpace(e.Stderr); stderr != "" {
+		msg = msg + ": " + stderr
+	}
+	return msg
+}
+
+func (e *PrimusError) Unwrap() error {
+	return e.Err
+}
+
+// fileError adds the operation and path to err. An *os.PathError already
+// names its path, so only the operation is added to it.
+func fileError(op string, path string, err error) error {
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return fmt.Errorf("%s %s: %w", op, path, err)
+}
+
+func newPrimusError(op string, err error) *PrimusError {
+	pe := &PrimusError{Op: op, ExitCode: -1, Err: err}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		pe.ExitCode = exitErr.ExitCode()
+		pe.Stderr = string(exitErr.Stderr)
+	}
+	return pe
+}
+
+// contextError matches both the given sentinel and the underlying context
+// error, so callers can use errors.Is with either.
+type contextError struct {
+	op       string
+	sentinel error
+	err      error
+}
+
+func (e *contextError) Error() string {
+	if e.err == context.Canceled {
+		return e.op + ": " + e.err.Error()
+	}
+	return e.op + ": " + e.sentinel.Error()
+}
+
+func (e *contextError) Is(target error) bool {
+	return target == e.sentinel && e.err == context.DeadlineExceeded
+}
+
+func (e *contextError) Unwrap() error {
+	return e.err
+}
+
+func newContextError(op string, sentinel error, err error) error {
+	return &contextError{op: op, sentinel: sentinel, err: err}
+}
+
+// QueryError is returned by the typed read functions when running the
+// query itself failed.
+type QueryError struct {
+	Op  string
+	Err error
+}
+
+func (e *QueryError) Error() string {
+	return e.Op + ": " + e.Err.Error()
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// JSONParseError is returned when the JSON output of a successful query
+// cannot be repaired or decoded.
+type JSONParseError struct {
+	Path string
+	Err  error
+}
+
+func (e *JSONParseError) Error() string {
+	return fmt.Sprintf("parsing JSON output %s: %s", e.Path, e.Err)
+}
+
+func (e *JSONParseError) Unwrap() error {
+	return e.Err
+}
+
+// XMLParseError is returned when the XML output of a successful query
+// cannot be decoded.
+type XMLParseError struct {
+	Path string
+	Err  error
+}
+
+func (e *XMLParseError) Error() string {
+	return fmt.Sprintf("parsing XML output %s: %s", e.Path, e.Err)
+}
+
+func (e *XMLParseError) Unwrap() error {
+	return e.Err
+}
+
+// CSVParseError is returned when the output of a successful query is not
+// valid CSV.
+type CSVParseError struct {
+	Err error
+}
+
+func (e *CSVParseError) Error() string {
+	return "parsing CSV output: " + e.Err.Error()
+}
+
+func (e *CSVParseError) Unwrap() error {
+	return e.Err
+}
+
+// DestinationError is returned when query output cannot be written to the
+// requested file.
+type DestinationError struct {
+	Path string
+	Err  error
+}
+
+func (e *DestinationError) Error() string {
+	return fmt.Sprintf("writing output to %s: %s", e.Path, e.Err)
+}
+
+func (e *DestinationError) Unwrap() error {
+	return e.Err
+}
+
+// ImportError is returned by an import that reported errors and whose
+// import data was written to RollbackPath.
+type ImportError struct {
+	ErrorCount   int
+	RollbackPath string
+}
+
+func (e *ImportError) Error() string {
+	return fmt.Sprintf("import failed with %d errors, import data written to %s", e.ErrorCount, e.RollbackPath)
+}
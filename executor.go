@@ -0,0 +1,95 @@
+package gopq
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+)
+
+// ImportRequest holds the primusquery arguments of an import run.
+type ImportRequest struct {
+	Host     string
+	Port     string
+	User     string
+	Password string
+	Loader   string
+	File     string
+}
+
+// Executor runs the primusquery binary. The Client writes and removes the
+// query files and handles validation, retries and errors around it. Each
+// method returns what the binary wrote to stderr.
+type Executor interface {
+	// Execute runs primusquery on a query file and discards its stdout.
+	Execute(ctx context.Context, binaryPath, queryFile string) (string, error)
+	// ExecuteAndRead runs primusquery on a query file and copies its
+	// stdout to w.
+	ExecuteAndRead(ctx context.Context, binaryPath, queryFile string, w io.Writer) (string, error)
+	// ExecuteImportQuery runs primusquery with an import file and copies
+	// its stdout to w.
+	ExecuteImportQuery(ctx context.Context, binaryPath string, req ImportRequest, w io.Writer) (string, error)
+	// Update runs primusquery -update against host and port and copies
+	// its stdout to w.
+	Update(ctx context.Context, binaryPath, host, port string, w io.Writer) (string, error)
+	// Version runs primusquery --version and copies its stdout to w. An
+	// error wrapping ErrBinaryNotFound is returned when there is no binary.
+	Version(ctx context.Context, binaryPath string, w io.Writer) (string, error)
+}
+
+// ProcessExecutor runs primusquery as a subprocess. It is the default
+// Executor.
+type ProcessExecutor struct{}
+
+func (ProcessExecutor) Execute(ctx context.Context, binaryPath, queryFile string) (string, error) {
+	return ProcessExecutor{}.ExecuteAndRead(ctx, binaryPath, queryFile, nil)
+}
+
+func (ProcessExecutor) ExecuteAndRead(ctx context.Context, binaryPath, queryFile string, w io.Writer) (string, error) {
+	return runProcess(exec.CommandContext(ctx, binaryPath, queryFile), w)
+}
+
+func (ProcessExecutor) ExecuteImportQuery(ctx context.Context, binaryPath string, req ImportRequest, w io.Writer) (string, error) {
+	return runProcess(exec.CommandContext(ctx, binaryPath, req.Host, req.Port, req.User, req.Password, req.Loader, "-i", req.File), w)
+}
+
+func (ProcessExecutor) Update(ctx context.Context, binaryPath, host, port string, w io.Writer) (string, error) {
+	return runProcess(exec.CommandContext(ctx, binaryPath, host, port, "-update"), w)
+}
+
+func (ProcessExecutor) Version(ctx context.Context, binaryPath string, w io.Writer) (string, error) {
+	path, err := exec.LookPath(binaryPath)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", binaryPath, ErrBinaryNotFound)
+	}
+	return runProcess(exec.CommandContext(ctx, path, "--version"), w)
+}
+
+func runProcess(cmd *exec.Cmd, w io.Writer) (string, error) {
+	var stderr bytes.Buffer
+	cmd.Stdout = w
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	return stderr.String(), err
+}
+
+func WithExecutor(e Executor) Option {
+	return func(c *Client) {
+		c.executor = e
+	}
+}
+
+// SetExecutor sets the Executor used by the package-level functions.
+func SetExecutor(e Executor) {
+	stdMu.Lock()
+	defer stdMu.Unlock()
+	std.executor = e
+}
+
+func (c *Client) exec() Executor {
+	if c.executor == nil {
+		return ProcessExecutor{}
+	}
+	return c.executor
+}
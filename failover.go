@@ -0,0 +1,57 @@
+package gopq
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+var connectionErrorHints = []string{"connect", "unreachable", "no route", "host not found"}
+
+// isConnectionError reports whether err means the host could not be
+// reached, as opposed to an authentication or query error.
+func isConnectionError(err error) bool {
+	if errors.Is(err, ErrQueryTimeout) {
+		return true
+	}
+	var pe *PrimusError
+	if errors.As(err, &pe) {
+		stderr := strings.ToLower(pe.Stderr)
+		for _, hint := range connectionErrorHints {
+			if strings.Contains(stderr, hint) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// withFailover runs op against the query host and, on connection errors,
+// against each of the client's FallbackHosts in order. It returns the host
+// of the last attempt.
+func (c *Client) withFailover(ctx context.Context, query PrimusQuery, op func(PrimusQuery) error) (string, error) {
+	hosts := append([]string{query.Host}, c.FallbackHosts...)
+	var err error
+	for i, host := range hosts {
+		query.Host = host
+		err = op(query)
+		if err == nil || !isConnectionError(err) || ctx.Err() != nil {
+			return host, err
+		}
+		if c.Debug && i < len(hosts)-1 {
+			c.Logger.Debugf("host %s unreachable, trying %s: %s", host, hosts[i+1], err)
+		}
+	}
+	return query.Host, err
+}
+
+// ExecuteAndReadWithHost works like ExecuteAndRead and also returns the
+// host that answered, which differs from query.Host after a failover.
+func (c *Client) ExecuteAndReadWithHost(ctx context.Context, query PrimusQuery, timeout int) (string, string, error) {
+	result, err := c.ExecuteAndCapture(ctx, query, timeout)
+	return result.Output, result.Host, err
+}
+
+func ExecuteAndReadWithHost(ctx context.Context, query PrimusQuery, timeout int) (string, string, error) {
+	return defaultClient().ExecuteAndReadWithHost(ctx, query, timeout)
+}
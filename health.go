@@ -0,0 +1,88 @@
+package gopq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// HealthcheckFailure tells which step of Healthcheck failed.
+type HealthcheckFailure int
+
+const (
+	HealthcheckNotFound HealthcheckFailure = iota + 1
+	HealthcheckNotExecutable
+	HealthcheckDidNotStart
+)
+
+func (f HealthcheckFailure) String() string {
+	switch f {
+	case HealthcheckNotFound:
+		return "binary not found"
+	case HealthcheckNotExecutable:
+		return "binary not executable"
+	case HealthcheckDidNotStart:
+		return "binary did not start"
+	}
+	return "unknown failure"
+}
+
+// HealthcheckError is returned by Healthcheck.
+type HealthcheckError struct {
+	Failure HealthcheckFailure
+	Path    string
+	Err     error
+}
+
+func (e *HealthcheckError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("healthcheck %s: %s", e.Path, e.Failure)
+	}
+	return fmt.Sprintf("healthcheck %s: %s: %s", e.Path, e.Failure, e.Err)
+}
+
+func (e *HealthcheckError) Unwrap() error {
+	return e.Err
+}
+
+func (e *HealthcheckError) Is(target error) bool {
+	return target == ErrBinaryNotFound && e.Failure == HealthcheckNotFound
+}
+
+// Healthcheck verifies that the primusquery binary exists, is executable
+// and starts when run with --version.
+func (c *Client) Healthcheck(ctx context.Context) error {
+	path := c.PrimusQueryPath
+	if !strings.ContainsRune(path, os.PathSeparator) {
+		found, err := exec.LookPath(path)
+		if err != nil {
+			return &HealthcheckError{Failure: HealthcheckNotFound, Path: path, Err: err}
+		}
+		path = found
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return &HealthcheckError{Failure: HealthcheckNotFound, Path: path, Err: err}
+	}
+	if info.IsDir() || info.Mode().Perm()&0111 == 0 {
+		return &HealthcheckError{Failure: HealthcheckNotExecutable, Path: path}
+	}
+
+	err = exec.CommandContext(ctx, path, "--version").Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return &HealthcheckError{Failure: HealthcheckDidNotStart, Path: path, Err: err}
+	}
+	if ctx.Err() != nil {
+		return &HealthcheckError{Failure: HealthcheckDidNotStart, Path: path, Err: ctx.Err()}
+	}
+	return nil
+}
+
+func Healthcheck(ctx context.Context) error {
+	return defaultClient().Healthcheck(ctx)
+}
@@ -0,0 +1,192 @@
+package gopq
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ImportResult is the outcome of an atomic import. NewCardID is -1 when
+// primusquery reported no new card.
+type ImportResult struct {
+	NewCardID  int
+	ErrorCount int
+	Warnings   []string
+	RawOutput  string
+}
+
+var warningLinePattern = regexp.MustCompile(`(?m)^\s*(?i:warning)\b:?(.*)$`)
+
+// importWarnings returns the messages of the "Warning: ..." lines in the
+// output.
+func importWarnings(output string) []string {
+	var warnings []string
+	for _, match := range warningLinePattern.FindAllStringSubmatch(output, -1) {
+		if msg := strings.TrimSpace(match[1]); msg != "" {
+			warnings = append(warnings, msg)
+		}
+	}
+	return warnings
+}
+
+// ImportOption configures a single import call.
+type ImportOption func(*importOptions)
+
+type importOptions struct {
+	rollbackPath string
+}
+
+// WithRollbackPath makes an import that reports errors write the original
+// import data to path for an operator to inspect.
+func WithRollbackPath(path string) ImportOption {
+	return func(o *importOptions) {
+		o.rollbackPath = path
+	}
+}
+
+// rollbackImport writes the import data of a failed import to rollbackPath
+// and removes the original import file if it is still there.
+func (c *Client) rollbackImport(filename string, data []byte, rollbackPath string, errorCount int) error {
+	err := os.WriteFile(rollbackPath, data, 0600)
+	if err != nil {
+		c.Logger.Errorf("cannot write rollback file: %s", rollbackPath)
+		return fileError("write rollback file", rollbackPath, err)
+	}
+	if FileExists(filename) {
+		if err := c.safeDelete(filename); err != nil {
+			return err
+		}
+	}
+	return &ImportError{ErrorCount: errorCount, RollbackPath: rollbackPath}
+}
+
+// ExecuteAtomicImportQueryBytes writes data to a temp import file and runs
+// ExecuteAtomicImportQuery on it. The temp file is removed in every case.
+func (c *Client) ExecuteAtomicImportQueryBytes(ctx context.Context, data []byte, primusHost, primusPort, userName, password, loaderName string, opts ...ImportOption) (ImportResult, error) {
+	filename, err := c.createTMPFile(c.tempFilePattern(""), string(data))
+	if err != nil {
+		return ImportResult{NewCardID: -1}, err
+	}
+	defer func() {
+		if FileExists(filename) {
+			_ = c.safeDelete(filename)
+		}
+	}()
+	return c.ExecuteAtomicImportQuery(ctx, filename, primusHost, primusPort, userName, password, loaderName, opts...)
+}
+
+func ExecuteAtomicImportQueryBytes(ctx context.Context, data []byte, primusHost, primusPort, userName, password, loaderName string, opts ...ImportOption) (ImportResult, error) {
+	return defaultClient().ExecuteAtomicImportQueryBytes(ctx, data, primusHost, primusPort, userName, password, loaderName, opts...)
+}
+
+// ImportMapper turns a record into the Primus import file format.
+type ImportMapper func(record interface{}) string
+
+// ExecuteAtomicImportQueryFromStruct imports record using mapper to write
+// the import file, see ExecuteAtomicImportQueryBytes.
+func (c *Client) ExecuteAtomicImportQueryFromStruct(ctx context.Context, record interface{}, mapper ImportMapper, primusHost, primusPort, userName, password, loaderName string, opts ...ImportOption) (ImportResult, error) {
+	if mapper == nil {
+		return ImportResult{NewCardID: -1}, fmt.Errorf("import query %s: mapper is nil", loaderName)
+	}
+	return c.ExecuteAtomicImportQueryBytes(ctx, []byte(mapper(record)), primusHost, primusPort, userName, password, loaderName, opts...)
+}
+
+func ExecuteAtomicImportQueryFromStruct(ctx context.Context, record interface{}, mapper ImportMapper, primusHost, primusPort, userName, password, loaderName string, opts ...ImportOption) (ImportResult, error) {
+	return defaultClient().ExecuteAtomicImportQueryFromStruct(ctx, record, mapper, primusHost, primusPort, userName, password, loaderName, opts...)
+}
+
+// BatchImportResult is the outcome of one file of ExecuteBatchImport.
+type BatchImportResult struct {
+	Index  int
+	File   string
+	Result ImportResult
+	Err    error
+}
+
+// ExecuteBatchImport imports the files with ExecuteAtomicImportQuery, up to
+// MaxConcurrent at a time, or all at once when MaxConcurrent is zero. A
+// result is returned for every file, in the order of files, and the error
+// reports how many of the imports failed. Imports not yet started when ctx
+// is done get the context error.
+func (c *Client) ExecuteBatchImport(ctx context.Context, files []string, primusHost, primusPort, userName, password, loaderName string, opts ...ImportOption) ([]BatchImportResult, error) {
+	results := make([]BatchImportResult, len(files))
+	concurrency := c.MaxConcurrent
+	if concurrency <= 0 || concurrency > len(files) {
+		concurrency = len(files)
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = BatchImportResult{Index: i, File: files[i], Result: ImportResult{NewCardID: -1}}
+				if err := ctx.Err(); err != nil {
+					results[i].Err = err
+					continue
+				}
+				results[i].Result, results[i].Err = c.ExecuteAtomicImportQuery(ctx, files[i], primusHost, primusPort, userName, password, loaderName, opts...)
+			}
+		}()
+	}
+	for i := range files {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var (
+		failed   int
+		firstErr error
+	)
+	for _, result := range results {
+		if result.Err != nil {
+			if firstErr == nil {
+				firstErr = result.Err
+			}
+			failed++
+		}
+	}
+	if failed > 0 {
+		return results, fmt.Errorf("batch import %s: %d of %d imports failed: %w", loaderName, failed, len(files), firstErr)
+	}
+	return results, nil
+}
+
+func ExecuteBatchImport(ctx context.Context, files []string, primusHost, primusPort, userName, password, loaderName string, opts ...ImportOption) ([]BatchImportResult, error) {
+	return defaultClient().ExecuteBatchImport(ctx, files, primusHost, primusPort, userName, password, loaderName, opts...)
+}
+
+// ExecuteImportQueryReader copies the import data from r to a temp import
+// file and runs ExecuteImportQuery on it. The temp file is removed in every
+// case.
+func (c *Client) ExecuteImportQueryReader(ctx context.Context, r io.Reader, primusHost, primusPort, userName, password, loaderName string) (string, error) {
+	file, err := c.createTempFile(c.tempFilePattern(""), "")
+	if err != nil {
+		return "", err
+	}
+	filename := file.Name()
+	defer func() {
+		if FileExists(filename) {
+			_ = c.safeDelete(filename)
+		}
+	}()
+	_, err = io.Copy(file, r)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fileError("write import-file", filename, err)
+	}
+	return c.ExecuteImportQuery(ctx, filename, primusHost, primusPort, userName, password, loaderName)
+}
+
+func ExecuteImportQueryReader(ctx context.Context, r io.Reader, primusHost, primusPort, userName, password, loaderName string) (string, error) {
+	return defaultClient().ExecuteImportQueryReader(ctx, r, primusHost, primusPort, userName, password, loaderName)
+}
@@ -0,0 +1,94 @@
+package gopq
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// acquire takes a slot of the client's MaxConcurrent semaphore, blocking
+// until one is free or ctx is done. When MaxQueueDepth callers are already
+// waiting ErrQueueFull is returned instead. The returned function releases
+// the slot.
+func (c *Client) acquire(ctx context.Context) (func(), error) {
+	if c.MaxConcurrent <= 0 {
+		return func() {}, nil
+	}
+	c.semOnce.Do(func() {
+		c.sem = make(chan struct{}, c.MaxConcurrent)
+	})
+	select {
+	case c.sem <- struct{}{}:
+		return func() { <-c.sem }, nil
+	default:
+	}
+
+	depth := atomic.AddInt32(&c.queued, 1)
+	defer atomic.AddInt32(&c.queued, -1)
+	if c.MaxQueueDepth > 0 && int(depth) > c.MaxQueueDepth {
+		return nil, ErrQueueFull
+	}
+	select {
+	case c.sem <- struct{}{}:
+		return func() { <-c.sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// QueueDepth returns the number of callers waiting for a MaxConcurrent slot.
+func (c *Client) QueueDepth() int {
+	return int(atomic.LoadInt32(&c.queued))
+}
+
+// rateLimiter is a token bucket refilled at the client's QueriesPerSecond,
+// holding up to one second's worth of tokens.
+type rateLimiter struct {
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// wait blocks until a query may start at the given rate or ctx is done.
+func (l *rateLimiter) wait(ctx context.Context, rate float64) error {
+	if rate <= 0 {
+		return nil
+	}
+	burst := math.Max(1, rate)
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.last.IsZero() {
+		l.tokens = burst
+	} else {
+		l.tokens = math.Min(burst, l.tokens+now.Sub(l.last).Seconds()*rate)
+	}
+	l.last = now
+	l.tokens--
+	delay := time.Duration(-l.tokens / rate * float64(time.Second))
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+func (l *rateLimiter) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens = 0
+	l.last = time.Time{}
+}
@@ -0,0 +1,43 @@
+package gopq
+
+import "log"
+
+// Logger receives the package's diagnostic output. Debug messages are only
+// emitted when Debug is enabled on the client.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+type stdLogger struct {
+	l *log.Logger
+}
+
+// NewStdLogger wraps a standard library logger as a Logger.
+func NewStdLogger(l *log.Logger) Logger {
+	return stdLogger{l: l}
+}
+
+func (s stdLogger) Debugf(format string, args ...interface{}) {
+	s.l.Printf(format, args...)
+}
+
+func (s stdLogger) Errorf(format string, args ...interface{}) {
+	s.l.Printf(format, args...)
+}
+
+type noopLogger struct{}
+
+func (noopLogger) Debugf(format string, args ...interface{}) {}
+
+func (noopLogger) Errorf(format string, args ...interface{}) {}
+
+// SetLogger sets the logger used by the package-level functions.
+func SetLogger(l Logger) {
+	if l == nil {
+		l = noopLogger{}
+	}
+	stdMu.Lock()
+	defer stdMu.Unlock()
+	std.Logger = l
+}
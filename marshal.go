@@ -0,0 +1,37 @@
+package gopq
+
+import "encoding/json"
+
+const maskedPassword = "***"
+
+// primusQueryJSON has the fields of PrimusQuery without its methods, so it
+// can be encoded without recursing into MarshalJSON.
+type primusQueryJSON PrimusQuery
+
+// MarshalJSON encodes the query with the password replaced by "***", so a
+// query can be logged safely. Use MarshalFull to keep the password.
+func (q PrimusQuery) MarshalJSON() ([]byte, error) {
+	if q.Pass != "" {
+		q.Pass = maskedPassword
+	}
+	return json.Marshal(primusQueryJSON(q))
+}
+
+// MarshalFull encodes the query including the real password.
+func (q PrimusQuery) MarshalFull() ([]byte, error) {
+	return json.Marshal(primusQueryJSON(q))
+}
+
+// UnmarshalJSON decodes a query, e.g. from a configuration file. A masked
+// password is decoded as empty for the caller to fill in.
+func (q *PrimusQuery) UnmarshalJSON(data []byte) error {
+	var decoded primusQueryJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	if decoded.Pass == maskedPassword {
+		decoded.Pass = ""
+	}
+	*q = PrimusQuery(decoded)
+	return nil
+}
@@ -0,0 +1,59 @@
+package gopq
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ClientMetrics is a snapshot of a client's counters. Query and import
+// runs are counted, retries and failover attempts each as a run of their
+// own. Failed includes the runs counted in TimedOut.
+type ClientMetrics struct {
+	Succeeded uint64 `json:"succeeded"`
+	Failed    uint64 `json:"failed"`
+	TimedOut  uint64 `json:"timedOut"`
+	CacheHits uint64 `json:"cacheHits"`
+	InFlight  uint64 `json:"inFlight"`
+}
+
+// clientCounters are updated atomically. The Client keeps them as its first
+// field so they are 64-bit aligned on 32-bit platforms.
+type clientCounters struct {
+	succeeded uint64
+	failed    uint64
+	timedOut  uint64
+	cacheHits uint64
+	inFlight  uint64
+}
+
+// Metrics returns the client's counters at this moment.
+func (c *Client) Metrics() ClientMetrics {
+	return ClientMetrics{
+		Succeeded: atomic.LoadUint64(&c.counters.succeeded),
+		Failed:    atomic.LoadUint64(&c.counters.failed),
+		TimedOut:  atomic.LoadUint64(&c.counters.timedOut),
+		CacheHits: atomic.LoadUint64(&c.counters.cacheHits),
+		InFlight:  atomic.LoadUint64(&c.counters.inFlight),
+	}
+}
+
+// countResult counts a finished query or import run.
+func (c *Client) countResult(err error) {
+	if err == nil {
+		atomic.AddUint64(&c.counters.succeeded, 1)
+		return
+	}
+	atomic.AddUint64(&c.counters.failed, 1)
+	if errors.Is(err, ErrQueryTimeout) {
+		atomic.AddUint64(&c.counters.timedOut, 1)
+	}
+}
+
+// resetCounters zeroes the counters except InFlight, which tracks runs that
+// are still going.
+func (c *Client) resetCounters() {
+	atomic.StoreUint64(&c.counters.succeeded, 0)
+	atomic.StoreUint64(&c.counters.failed, 0)
+	atomic.StoreUint64(&c.counters.timedOut, 0)
+	atomic.StoreUint64(&c.counters.cacheHits, 0)
+}
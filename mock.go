@@ -0,0 +1,119 @@
+package gopq
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+)
+
+// MockResponse is a canned primusquery result returned by MockExecutor.
+type MockResponse struct {
+	Output string
+	Stderr string
+	Err    error
+}
+
+// MockCall records one call made to a MockExecutor. Query is parsed from
+// the query file of Execute and ExecuteAndRead calls and has only Host and
+// Port set for Update calls. Import is set for ExecuteImportQuery calls.
+type MockCall struct {
+	Method string
+	Query  PrimusQuery
+	Import ImportRequest
+}
+
+// MockExecutor is an Executor for tests that do not have a primusquery
+// binary. Query responses are looked up by the query's Search, import
+// responses by the loader name and Update and Version responses by
+// MockUpdateKey and MockVersionKey, falling back to Default.
+type MockExecutor struct {
+	Default MockResponse
+
+	mu        sync.Mutex
+	responses map[string]MockResponse
+	calls     []MockCall
+}
+
+// Response keys of the MockExecutor Update and Version calls.
+const (
+	MockUpdateKey  = "-update"
+	MockVersionKey = "--version"
+)
+
+func NewMockExecutor() *MockExecutor {
+	return &MockExecutor{responses: map[string]MockResponse{}}
+}
+
+// SetResponse sets the response for queries with the given Search or
+// imports with the given loader name.
+func (m *MockExecutor) SetResponse(key string, response MockResponse) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.responses == nil {
+		m.responses = map[string]MockResponse{}
+	}
+	m.responses[key] = response
+}
+
+// Calls returns the calls made so far, oldest first.
+func (m *MockExecutor) Calls() []MockCall {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]MockCall(nil), m.calls...)
+}
+
+func (m *MockExecutor) Execute(ctx context.Context, binaryPath, queryFile string) (string, error) {
+	return m.query(ctx, "Execute", queryFile, nil)
+}
+
+func (m *MockExecutor) ExecuteAndRead(ctx context.Context, binaryPath, queryFile string, w io.Writer) (string, error) {
+	return m.query(ctx, "ExecuteAndRead", queryFile, w)
+}
+
+func (m *MockExecutor) ExecuteImportQuery(ctx context.Context, binaryPath string, req ImportRequest, w io.Writer) (string, error) {
+	response := m.record(MockCall{Method: "ExecuteImportQuery", Import: req}, req.Loader)
+	return m.respond(ctx, response, w)
+}
+
+func (m *MockExecutor) Update(ctx context.Context, binaryPath, host, port string, w io.Writer) (string, error) {
+	response := m.record(MockCall{Method: "Update", Query: PrimusQuery{Host: host, Port: port}}, MockUpdateKey)
+	return m.respond(ctx, response, w)
+}
+
+func (m *MockExecutor) Version(ctx context.Context, binaryPath string, w io.Writer) (string, error) {
+	response := m.record(MockCall{Method: "Version"}, MockVersionKey)
+	return m.respond(ctx, response, w)
+}
+
+func (m *MockExecutor) query(ctx context.Context, method, queryFile string, w io.Writer) (string, error) {
+	var query PrimusQuery
+	if f, err := os.Open(queryFile); err == nil {
+		query, _ = ParseQuery(f)
+		f.Close()
+	}
+	response := m.record(MockCall{Method: method, Query: query}, query.Search)
+	return m.respond(ctx, response, w)
+}
+
+func (m *MockExecutor) record(call MockCall, key string) MockResponse {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, call)
+	if response, ok := m.responses[key]; ok {
+		return response
+	}
+	return m.Default
+}
+
+func (m *MockExecutor) respond(ctx context.Context, response MockResponse, w io.Writer) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if w != nil && response.Output != "" {
+		if _, err := io.WriteString(w, response.Output); err != nil {
+			return response.Stderr, err
+		}
+	}
+	return response.Stderr, response.Err
+}
@@ -0,0 +1,72 @@
+package gopq
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMockExecutorAutoUpdate(t *testing.T) {
+	mock := NewMockExecutor()
+	mock.SetResponse("V1=Smith", MockResponse{Output: `[{"V1":"Smith"}]`})
+	c := NewClient("primusquery", WithExecutor(mock))
+	c.AutoUpdate = true
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.ExecuteAndRead(context.Background(), testQuery(), 0); err != nil {
+			t.Fatalf("ExecuteAndRead: %v", err)
+		}
+	}
+	if !c.IsUpdated() {
+		t.Error("client is not updated after AutoUpdate")
+	}
+
+	var methods []string
+	for _, call := range mock.Calls() {
+		methods = append(methods, call.Method)
+	}
+	want := []string{"Update", "ExecuteAndRead", "ExecuteAndRead"}
+	if len(methods) != len(want) {
+		t.Fatalf("calls = %v, want %v", methods, want)
+	}
+	for i := range want {
+		if methods[i] != want[i] {
+			t.Fatalf("calls = %v, want %v", methods, want)
+		}
+	}
+	if call := mock.Calls()[0]; call.Query.Host != testQuery().Host || call.Query.Port != testQuery().Port {
+		t.Errorf("Update called with %s:%s", call.Query.Host, call.Query.Port)
+	}
+}
+
+func TestMockExecutorForceUpdateFailure(t *testing.T) {
+	mock := NewMockExecutor()
+	mock.SetResponse(MockUpdateKey, MockResponse{Stderr: "connection refused", Err: errors.New("exit status 1")})
+	c := NewClient("primusquery", WithExecutor(mock))
+
+	err := c.ForceUpdatePQ(context.Background(), "primus.example.com", "1234", 0)
+	var pe *PrimusError
+	if !errors.As(err, &pe) {
+		t.Fatalf("ForceUpdatePQ() = %v, want *PrimusError", err)
+	}
+	if pe.Stderr != "connection refused" {
+		t.Errorf("Stderr = %q, want %q", pe.Stderr, "connection refused")
+	}
+	if c.IsUpdated() {
+		t.Error("client is updated after a failed update")
+	}
+}
+
+func TestMockExecutorVersion(t *testing.T) {
+	mock := NewMockExecutor()
+	mock.SetResponse(MockVersionKey, MockResponse{Output: "primusquery version 5.12.3\n"})
+	c := NewClient("primusquery", WithExecutor(mock))
+
+	version, err := c.GetPrimusVersion(context.Background())
+	if err != nil {
+		t.Fatalf("GetPrimusVersion: %v", err)
+	}
+	if version != "5.12.3" {
+		t.Errorf("GetPrimusVersion() = %q, want %q", version, "5.12.3")
+	}
+}
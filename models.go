@@ -1,16 +1,29 @@
 package gopq
 
 type PrimusQuery struct {
-	Charset  string
-	Host     string
-	Port     string
-	User     string
-	Pass     string
-	Output   string
-	Database string
-	Search   string
-	Sort     string
-	Header   string
-	Data     string
-	Footer   string
+	Charset  Charset `json:"charset,omitempty"`
+	Host     string  `json:"host,omitempty"`
+	Port     string  `json:"port,omitempty"`
+	User     string  `json:"user,omitempty"`
+	Pass     string  `json:"pass,omitempty"`
+	Output   string  `json:"output,omitempty"`
+	Database string  `json:"database,omitempty"`
+	Search   string  `json:"search,omitempty"`
+	Sort     string  `json:"sort,omitempty"`
+	Header   string  `json:"header,omitempty"`
+	Data     string  `json:"data,omitempty"`
+	Footer   string  `json:"footer,omitempty"`
+	// Limit and Offset page through the result when Limit is non-zero;
+	// Offset records are skipped before Limit records are returned.
+	Limit  int `json:"limit,omitempty"`
+	Offset int `json:"offset,omitempty"`
+	// TimeoutSeconds is the timeout of this query. The timeout argument
+	// of the execute functions is deprecated but wins when non-zero.
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+	// AllowCustomCharset lets Validate accept a Charset that is not one
+	// of ValidCharsets.
+	AllowCustomCharset bool `json:"allowCustomCharset,omitempty"`
+	// QueryID correlates log and audit entries of one logical query, see
+	// NewQueryID. It is not written to the query file.
+	QueryID string `json:"queryId,omitempty"`
 }
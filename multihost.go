@@ -0,0 +1,66 @@
+package gopq
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// HostClient pairs a Client with the Primus host its queries are sent to.
+// An empty Host keeps the host of the query.
+type HostClient struct {
+	Host   string
+	Client *Client
+}
+
+// MultiHostClient spreads read queries over several Primus hosts in turn
+// and sends imports to the primary host.
+type MultiHostClient struct {
+	// next is used atomically and kept first for 64-bit alignment.
+	next uint64
+
+	primary HostClient
+	readers []HostClient
+}
+
+var _ QueryRunner = (*MultiHostClient)(nil)
+
+// NewMultiHostClient returns a MultiHostClient reading from replicas, or
+// from primary when there are none.
+func NewMultiHostClient(primary HostClient, replicas ...HostClient) *MultiHostClient {
+	readers := replicas
+	if len(readers) == 0 {
+		readers = []HostClient{primary}
+	}
+	return &MultiHostClient{primary: primary, readers: readers}
+}
+
+// reader returns the next read host and query with its Host set to it.
+func (m *MultiHostClient) reader(query PrimusQuery) (*Client, PrimusQuery) {
+	n := atomic.AddUint64(&m.next, 1) - 1
+	hc := m.readers[n%uint64(len(m.readers))]
+	if hc.Host != "" {
+		query.Host = hc.Host
+	}
+	return hc.Client, query
+}
+
+func (m *MultiHostClient) Execute(ctx context.Context, query PrimusQuery, timeout int) error {
+	client, query := m.reader(query)
+	return client.Execute(ctx, query, timeout)
+}
+
+func (m *MultiHostClient) ExecuteAndRead(ctx context.Context, query PrimusQuery, timeout int) (string, error) {
+	client, query := m.reader(query)
+	return client.ExecuteAndRead(ctx, query, timeout)
+}
+
+// ExecuteImportQuery runs the import on the primary host, which must have
+// its Host set.
+func (m *MultiHostClient) ExecuteImportQuery(ctx context.Context, filename string, primusPort, userName, password, loaderName string) (string, error) {
+	return m.primary.Client.ExecuteImportQuery(ctx, filename, m.primary.Host, primusPort, userName, password, loaderName)
+}
+
+// ExecuteAtomicImportQuery runs the atomic import on the primary host.
+func (m *MultiHostClient) ExecuteAtomicImportQuery(ctx context.Context, filename string, primusPort, userName, password, loaderName string, opts ...ImportOption) (ImportResult, error) {
+	return m.primary.Client.ExecuteAtomicImportQuery(ctx, filename, m.primary.Host, primusPort, userName, password, loaderName, opts...)
+}
@@ -0,0 +1,164 @@
+package gopq
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ParseQuery reads a query file in the format written by QueryText. Lines
+// that are not directives or inside a header or footer block form the Data
+// section.
+//
+// ParseQuery(strings.NewReader(q.QueryText())) returns q for a valid query
+// except for the fields QueryText normalises: an empty Sort comes back as
+// "V1", the default QueryText writes, and the spaces in Sort are collapsed.
+// Validate rejects Data lines starting with a directive, which would not
+// come back as Data.
+func ParseQuery(r io.Reader) (PrimusQuery, error) {
+	return parseQuery(r, nil)
+}
+
+// LoadQueryFile reads a query file written by QueryText. Unlike ParseQuery,
+// directive lines it does not know are logged and left out of Data, so
+// files from newer primusquery versions still load.
+func (c *Client) LoadQueryFile(path string) (PrimusQuery, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return PrimusQuery{}, fileError("load query file", path, err)
+	}
+	defer file.Close()
+	query, err := parseQuery(file, func(lineNo int, name string) {
+		c.Logger.Errorf("%s:%d: ignoring unknown directive %s", path, lineNo, name)
+	})
+	if err != nil {
+		return PrimusQuery{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return query, nil
+}
+
+func LoadQueryFile(path string) (PrimusQuery, error) {
+	return defaultClient().LoadQueryFile(path)
+}
+
+var directivePattern = regexp.MustCompile(`^#[A-Z][A-Z_]*$`)
+
+// queryDirectives are the directives written by QueryText.
+var queryDirectives = map[string]bool{
+	"#CHARSET":      true,
+	"#HOST":         true,
+	"#PORT":         true,
+	"#USER":         true,
+	"#PASS":         true,
+	"#OUTPUT":       true,
+	"#DATABASE":     true,
+	"#SEARCH":       true,
+	"#SORT":         true,
+	"#LIMIT":        true,
+	"#OFFSET":       true,
+	"#HEADER_START": true,
+	"#FOOTER_START": true,
+}
+
+// directiveName returns the first word of a query file line.
+func directiveName(line string) string {
+	if i := strings.IndexByte(line, ' '); i >= 0 {
+		return line[:i]
+	}
+	return line
+}
+
+// parseQuery parses a query file. When unknown is set, directives that are
+// not recognised are passed to it instead of being kept as Data.
+func parseQuery(r io.Reader, unknown func(lineNo int, name string)) (PrimusQuery, error) {
+	var (
+		query     PrimusQuery
+		data      []string
+		block     []string
+		blockName string
+		lineNo    int
+	)
+	var charset string
+	directives := map[string]*string{
+		"#CHARSET":  &charset,
+		"#HOST":     &query.Host,
+		"#PORT":     &query.Port,
+		"#USER":     &query.User,
+		"#PASS":     &query.Pass,
+		"#OUTPUT":   &query.Output,
+		"#DATABASE": &query.Database,
+		"#SEARCH":   &query.Search,
+		"#SORT":     &query.Sort,
+	}
+	pagingDirectives := map[string]*int{
+		"#LIMIT":  &query.Limit,
+		"#OFFSET": &query.Offset,
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+
+		if blockName != "" {
+			if line == "#"+blockName+"_STOP" {
+				if blockName == "HEADER" {
+					query.SetHeaderLines(block)
+				} else {
+					query.SetFooterLines(block)
+				}
+				blockName, block = "", nil
+				continue
+			}
+			block = append(block, line)
+			continue
+		}
+
+		switch line {
+		case "#HEADER_START":
+			blockName = "HEADER"
+			continue
+		case "#FOOTER_START":
+			blockName = "FOOTER"
+			continue
+		}
+
+		name := line
+		value := ""
+		if i := strings.IndexByte(line, ' '); i >= 0 {
+			name, value = line[:i], line[i+1:]
+		}
+		if field, ok := directives[name]; ok {
+			*field = value
+			continue
+		}
+		if field, ok := pagingDirectives[name]; ok {
+			n, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return PrimusQuery{}, fmt.Errorf("%w: line %d: %s %q is not a number", ErrInvalidQuery, lineNo, name, value)
+			}
+			*field = n
+			continue
+		}
+		if unknown != nil && directivePattern.MatchString(name) {
+			unknown(lineNo, name)
+			continue
+		}
+		data = append(data, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return PrimusQuery{}, err
+	}
+	if blockName != "" {
+		return PrimusQuery{}, fmt.Errorf("%w: line %d: #%s_START without #%s_STOP", ErrInvalidQuery, lineNo, blockName, blockName)
+	}
+
+	query.Charset = Charset(charset)
+	query.SetDataLines(data)
+	return query, nil
+}
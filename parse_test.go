@@ -0,0 +1,99 @@
+package gopq
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testQuery() PrimusQuery {
+	return PrimusQuery{
+		Charset:  CharsetUTF8,
+		Host:     "primus.example.com",
+		Port:     "1234",
+		User:     "user",
+		Pass:     "secret",
+		Database: "students",
+		Search:   "V1=Smith",
+		Sort:     "V2 DESC",
+	}
+}
+
+func TestParseQueryRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*PrimusQuery)
+	}{
+		{"plain", func(q *PrimusQuery) {}},
+		{"output", func(q *PrimusQuery) { q.Output = "/tmp/out.json" }},
+		{"header and footer", func(q *PrimusQuery) {
+			q.SetHeaderLines([]string{"[", "#FIELD V1"})
+			q.SetFooterLines([]string{"#FIELD V2", "]"})
+		}},
+		{"limit and offset", func(q *PrimusQuery) {
+			q.Limit = 10
+			q.Offset = 20
+		}},
+		{"data", func(q *PrimusQuery) { q.SetDataLines([]string{"V1", "V2"}) }},
+		{"data starting with #", func(q *PrimusQuery) { q.SetDataLines([]string{"#1 row", "#NOTE kept"}) }},
+		{"data with blank lines", func(q *PrimusQuery) { q.SetDataLines([]string{"V1", "", "V2"}) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := testQuery().Clone(tt.modify)
+			if err := want.Validate(); err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			got, err := ParseQuery(strings.NewReader(want.QueryText()))
+			if err != nil {
+				t.Fatalf("ParseQuery: %v", err)
+			}
+			if got != want {
+				t.Errorf("ParseQuery(QueryText()) = %#v, want %#v", got, want)
+			}
+		})
+	}
+}
+
+func TestParseQueryNormalisedSort(t *testing.T) {
+	tests := []struct {
+		sort string
+		want string
+	}{
+		{"", "V1"},
+		{"  ", "V1"},
+		{"V2   V3  DESC", "V2 V3 DESC"},
+	}
+	for _, tt := range tests {
+		q := testQuery()
+		q.Sort = tt.sort
+		got, err := ParseQuery(strings.NewReader(q.QueryText()))
+		if err != nil {
+			t.Fatalf("ParseQuery: %v", err)
+		}
+		if got.Sort != tt.want {
+			t.Errorf("Sort %q comes back as %q, want %q", tt.sort, got.Sort, tt.want)
+		}
+	}
+}
+
+func TestValidateRejectsDirectivesInBlocks(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*PrimusQuery)
+	}{
+		{"data directive", func(q *PrimusQuery) { q.SetDataLines([]string{"V1", "#HOST evil"}) }},
+		{"data directive without value", func(q *PrimusQuery) { q.Data = "#LIMIT" }},
+		{"data block start", func(q *PrimusQuery) { q.Data = "#HEADER_START" }},
+		{"header stop", func(q *PrimusQuery) { q.SetHeaderLines([]string{"[", "#HEADER_STOP"}) }},
+		{"footer stop", func(q *PrimusQuery) { q.Footer = "#FOOTER_STOP" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := testQuery().Clone(tt.modify).Validate()
+			if !errors.Is(err, ErrInvalidQuery) {
+				t.Errorf("Validate() = %v, want ErrInvalidQuery", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package gopq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// PingPrimus runs a probe query without search or credentials against the
+// host. A timeout is reported as ErrUnreachable.
+func (c *Client) PingPrimus(ctx context.Context, host, port string, timeout int) error {
+	// the probe skips Validate, which would require a search and
+	// credentials, but host and port still go into directive lines
+	if err := checkSingleLine([]namedValue{{"Host", host}, {"Port", port}}); err != nil {
+		return fmt.Errorf("ping: %w", err)
+	}
+	if err := validatePort(port); err != nil {
+		return fmt.Errorf("ping: %w", err)
+	}
+	probe := PrimusQuery{Host: host, Port: port}
+	_, err := c.runQuery(ctx, "ping "+host+":"+port, probe, timeout, nil)
+	if errors.Is(err, ErrQueryTimeout) {
+		return newContextError("ping "+host+":"+port, ErrUnreachable, context.DeadlineExceeded)
+	}
+	return err
+}
+
+func PingPrimus(ctx context.Context, host, port string, timeout int) error {
+	return defaultClient().PingPrimus(ctx, host, port, timeout)
+}
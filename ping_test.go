@@ -0,0 +1,46 @@
+package gopq
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPingPrimusRejectsInvalidAddress(t *testing.T) {
+	tests := []struct {
+		name string
+		host string
+		port string
+	}{
+		{"host with newline", "h\n#OUTPUT /some/path", "1234"},
+		{"host with carriage return", "h\r#OUTPUT /some/path", "1234"},
+		{"port with newline", "h", "1234\n#OUTPUT /some/path"},
+		{"port not a number", "h", "http"},
+		{"port out of range", "h", "70000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := NewMockExecutor()
+			c := NewClient("primusquery", WithExecutor(mock))
+			err := c.PingPrimus(context.Background(), tt.host, tt.port, 1)
+			if !errors.Is(err, ErrInvalidQuery) {
+				t.Errorf("PingPrimus() = %v, want ErrInvalidQuery", err)
+			}
+			if calls := mock.Calls(); len(calls) != 0 {
+				t.Errorf("executor called %d times", len(calls))
+			}
+		})
+	}
+}
+
+func TestPingPrimus(t *testing.T) {
+	mock := NewMockExecutor()
+	c := NewClient("primusquery", WithExecutor(mock))
+	if err := c.PingPrimus(context.Background(), "primus.example.com", "1234", 1); err != nil {
+		t.Fatalf("PingPrimus: %v", err)
+	}
+	calls := mock.Calls()
+	if len(calls) != 1 || calls[0].Query.Host != "primus.example.com" || calls[0].Query.Port != "1234" {
+		t.Errorf("calls = %+v, want one probe of primus.example.com:1234", calls)
+	}
+}
@@ -0,0 +1,27 @@
+package gopq
+
+import "sync"
+
+// QueryPool reuses PrimusQuery values in code that builds many short-lived
+// queries. The zero value is ready to use.
+type QueryPool struct {
+	pool sync.Pool
+}
+
+// Get returns a zeroed query from the pool or a new one.
+func (p *QueryPool) Get() *PrimusQuery {
+	if q, ok := p.pool.Get().(*PrimusQuery); ok {
+		return q
+	}
+	return &PrimusQuery{}
+}
+
+// Put zeroes q, dropping its credentials, and returns it to the pool. q must
+// not be used after Put.
+func (p *QueryPool) Put(q *PrimusQuery) {
+	if q == nil {
+		return
+	}
+	*q = PrimusQuery{}
+	p.pool.Put(q)
+}
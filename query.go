@@ -0,0 +1,288 @@
+package gopq
+
+import (
+	cryptorand "crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var sortColumnPattern = regexp.MustCompile(`^[A-Za-z0-9]+$`)
+
+// Validate checks the query before it is written to a query file and
+// passed to primusquery.
+func (q PrimusQuery) Validate() error {
+	if err := q.validateConnection(); err != nil {
+		return err
+	}
+	if strings.TrimSpace(q.Search) == "" {
+		return fmt.Errorf("%w: Search is empty", ErrInvalidQuery)
+	}
+	if err := checkSingleLine([]namedValue{
+		{"Search", q.Search},
+		{"Sort", q.Sort},
+		{"Output", q.Output},
+	}); err != nil {
+		return err
+	}
+	if q.Limit < 0 || q.Offset < 0 {
+		return fmt.Errorf("%w: Limit and Offset must not be negative", ErrInvalidQuery)
+	}
+	if q.Offset > 0 && q.Limit == 0 {
+		return fmt.Errorf("%w: Offset is set without Limit", ErrInvalidQuery)
+	}
+	if err := q.validateBlocks(); err != nil {
+		return err
+	}
+	return validateSort(q.Sort)
+}
+
+// validateBlocks rejects Data lines that would be read as directives and
+// header or footer lines that would end their block early.
+func (q PrimusQuery) validateBlocks() error {
+	for i, line := range q.DataLines() {
+		if name := directiveName(strings.TrimSuffix(line, "\r")); queryDirectives[name] {
+			return fmt.Errorf("%w: Data line %d starts with the %s directive", ErrInvalidQuery, i+1, name)
+		}
+	}
+	for _, block := range []struct {
+		name  string
+		stop  string
+		lines []string
+	}{
+		{"Header", "#HEADER_STOP", q.HeaderLines()},
+		{"Footer", "#FOOTER_STOP", q.FooterLines()},
+	} {
+		for i, line := range block.lines {
+			if strings.TrimSuffix(line, "\r") == block.stop {
+				return fmt.Errorf("%w: %s line %d is %s", ErrInvalidQuery, block.name, i+1, block.stop)
+			}
+		}
+	}
+	return nil
+}
+
+// validateConnection checks the fields needed to connect to the Primus
+// database.
+func (q PrimusQuery) validateConnection() error {
+	mandatory := []namedValue{
+		{"Host", q.Host},
+		{"Port", q.Port},
+		{"User", q.User},
+		{"Pass", q.Pass},
+		{"Database", q.Database},
+		{"Charset", string(q.Charset)},
+	}
+	for _, field := range mandatory {
+		if strings.TrimSpace(field.value) == "" {
+			return fmt.Errorf("%w: %s is empty", ErrInvalidQuery, field.name)
+		}
+	}
+	if err := checkSingleLine(mandatory); err != nil {
+		return err
+	}
+	if err := validatePort(q.Port); err != nil {
+		return err
+	}
+	if !q.AllowCustomCharset && !q.Charset.IsValid() {
+		return fmt.Errorf("%w: unsupported charset %q", ErrInvalidQuery, q.Charset)
+	}
+	return nil
+}
+
+// validatePort checks that port is a TCP port number.
+func validatePort(port string) error {
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("%w: invalid port %q", ErrInvalidQuery, port)
+	}
+	return nil
+}
+
+type namedValue struct {
+	name  string
+	value string
+}
+
+// checkSingleLine rejects values with a CR or LF, which would end their
+// directive line and let the rest be read as further directives.
+func checkSingleLine(fields []namedValue) error {
+	for _, field := range fields {
+		if strings.ContainsAny(field.value, "\r\n") {
+			return fmt.Errorf("%w: %s contains a line break", ErrInvalidQuery, field.name)
+		}
+	}
+	return nil
+}
+
+// validateSort accepts space-separated column names, each optionally
+// followed by ASC or DESC, e.g. "V2 V3 DESC".
+func validateSort(sort string) error {
+	previousColumn := false
+	for _, token := range strings.Fields(sort) {
+		switch strings.ToUpper(token) {
+		case "ASC", "DESC":
+			if !previousColumn {
+				return fmt.Errorf("%w: sort direction %q without column", ErrInvalidQuery, token)
+			}
+			previousColumn = false
+		default:
+			if !sortColumnPattern.MatchString(token) {
+				return fmt.Errorf("%w: invalid sort column %q", ErrInvalidQuery, token)
+			}
+			previousColumn = true
+		}
+	}
+	return nil
+}
+
+// Clone returns a copy of the query with the overrides applied to the copy,
+// e.g. q.Clone(func(p *PrimusQuery) { p.Search = "V1=Smith" }).
+func (q PrimusQuery) Clone(overrides ...func(*PrimusQuery)) PrimusQuery {
+	clone := q
+	for _, override := range overrides {
+		override(&clone)
+	}
+	return clone
+}
+
+// Fingerprint returns a stable SHA-256 hex digest of the fields that decide
+// a query's result. User and Pass are left out, so the same query run with
+// different credentials has the same fingerprint.
+func (q PrimusQuery) Fingerprint() string {
+	h := sha256.New()
+	for _, field := range []string{
+		q.Host,
+		q.Port,
+		q.Database,
+		q.Search,
+		q.Sort,
+		q.Data,
+		q.Header,
+		q.Footer,
+		string(q.Charset),
+		strconv.Itoa(q.Limit),
+		strconv.Itoa(q.Offset),
+	} {
+		// length prefixes keep field boundaries unambiguous
+		fmt.Fprintf(h, "%d:%s;", len(field), field)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Equal reports whether q and other decide the same result, comparing the
+// same fields as Fingerprint. User and Pass are ignored.
+func (q PrimusQuery) Equal(other PrimusQuery) bool {
+	return q.Host == other.Host &&
+		q.Port == other.Port &&
+		q.Database == other.Database &&
+		q.Search == other.Search &&
+		q.Sort == other.Sort &&
+		q.Data == other.Data &&
+		q.Header == other.Header &&
+		q.Footer == other.Footer &&
+		q.Charset == other.Charset &&
+		q.Limit == other.Limit &&
+		q.Offset == other.Offset
+}
+
+// RedactedPass is the Pass of a query returned by Redacted.
+const RedactedPass = "[REDACTED]"
+
+// Redacted returns a copy of q that is safe to log: Pass is replaced with
+// RedactedPass and User is masked to its first character. q is not changed.
+func (q PrimusQuery) Redacted() PrimusQuery {
+	if q.Pass != "" {
+		q.Pass = RedactedPass
+	}
+	q.User = maskUser(q.User)
+	return q
+}
+
+func maskUser(user string) string {
+	for _, r := range user {
+		return string(r) + "***"
+	}
+	return ""
+}
+
+// WithCredentials returns a copy of q with User and Pass set, leaving q
+// unchanged for reuse as a base query.
+func (q PrimusQuery) WithCredentials(user, pass string) PrimusQuery {
+	q.User = user
+	q.Pass = pass
+	return q
+}
+
+// NewQueryID returns a random 128-bit hex encoded ID for PrimusQuery.QueryID.
+func NewQueryID() string {
+	b := make([]byte, 16)
+	if _, err := cryptorand.Read(b); err != nil {
+		panic("gopq: crypto/rand failed: " + err.Error())
+	}
+	return hex.EncodeToString(b)
+}
+
+// String returns a short summary of the query for logs, with the user
+// masked as in Redacted and without the password.
+func (q PrimusQuery) String() string {
+	r := q.Redacted()
+	return fmt.Sprintf("PrimusQuery{host:%s, db:%s, search:%s, user:%s}", r.Host, r.Database, r.Search, r.User)
+}
+
+// GoString returns the complete struct, including the password, for
+// explicit %#v formatting.
+func (q PrimusQuery) GoString() string {
+	return strings.Replace(fmt.Sprintf("%#v", primusQueryJSON(q)), "gopq.primusQueryJSON", "gopq.PrimusQuery", 1)
+}
+
+// DataLines returns the lines of the Data section, nil when it is empty.
+func (q PrimusQuery) DataLines() []string {
+	return splitBlock(q.Data)
+}
+
+// SetDataLines replaces the Data section with lines joined by "\n".
+func (q *PrimusQuery) SetDataLines(lines []string) {
+	q.Data = strings.Join(lines, "\n")
+}
+
+// AppendDataLine adds a line to the end of the Data section and returns q
+// for chaining.
+func (q *PrimusQuery) AppendDataLine(line string) *PrimusQuery {
+	if q.Data == "" {
+		q.Data = line
+	} else {
+		q.Data += "\n" + line
+	}
+	return q
+}
+
+// HeaderLines returns the lines of the header block, nil when it is empty.
+func (q PrimusQuery) HeaderLines() []string {
+	return splitBlock(q.Header)
+}
+
+// SetHeaderLines replaces the header block with lines joined by "\n".
+func (q *PrimusQuery) SetHeaderLines(lines []string) {
+	q.Header = strings.Join(lines, "\n")
+}
+
+// FooterLines returns the lines of the footer block, nil when it is empty.
+func (q PrimusQuery) FooterLines() []string {
+	return splitBlock(q.Footer)
+}
+
+// SetFooterLines replaces the footer block with lines joined by "\n".
+func (q *PrimusQuery) SetFooterLines(lines []string) {
+	q.Footer = strings.Join(lines, "\n")
+}
+
+func splitBlock(block string) []string {
+	if block == "" {
+		return nil
+	}
+	return strings.Split(block, "\n")
+}
@@ -0,0 +1,147 @@
+package gopq
+
+import (
+	"testing"
+)
+
+func TestClone(t *testing.T) {
+	tests := []struct {
+		name     string
+		override func(*PrimusQuery)
+		check    func(original, clone PrimusQuery) bool
+	}{
+		{
+			name:     "search",
+			override: func(q *PrimusQuery) { q.Search = "V1=Jones" },
+			check: func(original, clone PrimusQuery) bool {
+				return original.Search == "V1=Smith" && clone.Search == "V1=Jones"
+			},
+		},
+		{
+			name:     "credentials",
+			override: func(q *PrimusQuery) { q.User, q.Pass = "other", "other-secret" },
+			check: func(original, clone PrimusQuery) bool {
+				return original.User == "user" && original.Pass == "secret" &&
+					clone.User == "other" && clone.Pass == "other-secret"
+			},
+		},
+		{
+			name:     "paging",
+			override: func(q *PrimusQuery) { q.Limit, q.Offset = 5, 10 },
+			check: func(original, clone PrimusQuery) bool {
+				return original.Limit == 0 && original.Offset == 0 && clone.Limit == 5 && clone.Offset == 10
+			},
+		},
+		{
+			name:     "data lines",
+			override: func(q *PrimusQuery) { q.AppendDataLine("V3") },
+			check: func(original, clone PrimusQuery) bool {
+				return original.Data == "" && clone.Data == "V3"
+			},
+		},
+		{
+			name:     "no overrides",
+			override: func(q *PrimusQuery) {},
+			check: func(original, clone PrimusQuery) bool {
+				return original == clone
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := testQuery()
+			clone := original.Clone(tt.override)
+			if original != testQuery() {
+				t.Fatalf("Clone changed the original: %#v", original)
+			}
+			if !tt.check(original, clone) {
+				t.Errorf("Clone() = %#v, original %#v", clone, original)
+			}
+		})
+	}
+}
+
+func TestCloneAppliesOverridesInOrder(t *testing.T) {
+	clone := testQuery().Clone(
+		func(q *PrimusQuery) { q.Search = "V1=first" },
+		func(q *PrimusQuery) { q.Search += " OR V1=second" },
+	)
+	if want := "V1=first OR V1=second"; clone.Search != want {
+		t.Errorf("Search = %q, want %q", clone.Search, want)
+	}
+}
+
+func TestEqual(t *testing.T) {
+	a := testQuery()
+	b := testQuery().WithCredentials("other", "other-secret")
+	c := testQuery().Clone(func(q *PrimusQuery) { q.User = "third" })
+	different := testQuery().Clone(func(q *PrimusQuery) { q.Search = "V1=Jones" })
+
+	if !a.Equal(a) {
+		t.Error("Equal is not reflexive")
+	}
+	if !a.Equal(b) || !b.Equal(a) {
+		t.Error("queries differing only in credentials are not equal both ways")
+	}
+	if !b.Equal(c) || !a.Equal(c) {
+		t.Error("Equal is not transitive")
+	}
+	if a.Equal(different) || different.Equal(a) {
+		t.Error("queries with different searches are equal")
+	}
+	if a.Fingerprint() != b.Fingerprint() {
+		t.Error("Equal queries have different fingerprints")
+	}
+}
+
+func TestEqualFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*PrimusQuery)
+	}{
+		{"host", func(q *PrimusQuery) { q.Host = "other" }},
+		{"port", func(q *PrimusQuery) { q.Port = "4321" }},
+		{"database", func(q *PrimusQuery) { q.Database = "other" }},
+		{"search", func(q *PrimusQuery) { q.Search = "V1=Jones" }},
+		{"sort", func(q *PrimusQuery) { q.Sort = "V3" }},
+		{"data", func(q *PrimusQuery) { q.Data = "V3" }},
+		{"header", func(q *PrimusQuery) { q.Header = "[" }},
+		{"footer", func(q *PrimusQuery) { q.Footer = "]" }},
+		{"charset", func(q *PrimusQuery) { q.Charset = CharsetLatin1 }},
+		{"limit", func(q *PrimusQuery) { q.Limit = 1 }},
+		{"offset", func(q *PrimusQuery) { q.Offset = 1 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := testQuery(), testQuery().Clone(tt.modify)
+			if a.Equal(b) || b.Equal(a) {
+				t.Errorf("queries differing in %s are equal", tt.name)
+			}
+			if a.Fingerprint() == b.Fingerprint() {
+				t.Errorf("queries differing in %s have the same fingerprint", tt.name)
+			}
+		})
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	original := testQuery()
+	redacted := original.Redacted()
+
+	if redacted.Pass != RedactedPass {
+		t.Errorf("Pass = %q, want %q", redacted.Pass, RedactedPass)
+	}
+	if redacted.User != "u***" {
+		t.Errorf("User = %q, want %q", redacted.User, "u***")
+	}
+	if original != testQuery() {
+		t.Errorf("Redacted changed the original: %#v", original)
+	}
+}
+
+func TestRedactedEmptyCredentials(t *testing.T) {
+	q := testQuery().WithCredentials("", "")
+	if r := q.Redacted(); r.User != "" || r.Pass != "" {
+		t.Errorf("Redacted() credentials = %q, %q, want empty", r.User, r.Pass)
+	}
+}
@@ -0,0 +1,125 @@
+package gopq
+
+import (
+	"os"
+	"runtime"
+)
+
+// memoryTempDir is the memory backed tmpfs used for query files when
+// Client.SecureQueryFile is set on Linux.
+const memoryTempDir = "/dev/shm"
+
+// QueryFile is a .priq file on disk holding one query for primusquery. It
+// is created in the client's TempDir on the first Write and must be removed
+// with Delete or SafeDelete once the query has run. The file stays open
+// until then so it is overwritten through the same handle it was created
+// with.
+type QueryFile struct {
+	client   *Client
+	file     *os.File
+	inMemory bool
+}
+
+// NewQueryFile returns a QueryFile for c. No file is created before Write.
+func (c *Client) NewQueryFile() *QueryFile {
+	return &QueryFile{client: c}
+}
+
+func NewQueryFile() *QueryFile {
+	return defaultClient().NewQueryFile()
+}
+
+// Write stores the query text of q in the file, creating it on first use
+// and replacing its content after that.
+func (f *QueryFile) Write(q PrimusQuery) error {
+	text := q.QueryText()
+	if f.file == nil {
+		return f.create(text)
+	}
+	err := f.file.Truncate(0)
+	if err == nil {
+		_, err = f.file.WriteAt([]byte(text), 0)
+	}
+	if err != nil {
+		return fileError("write query file", f.file.Name(), err)
+	}
+	return nil
+}
+
+// Path returns the file's path, empty before the first Write.
+func (f *QueryFile) Path() string {
+	if f.file == nil {
+		return ""
+	}
+	return f.file.Name()
+}
+
+// Delete removes the file without overwriting it. It does nothing if the
+// file was never written or is already deleted.
+func (f *QueryFile) Delete() error {
+	if f.file == nil {
+		return nil
+	}
+	return f.remove()
+}
+
+// SafeDelete overwrites the file SafeDeletePasses times before removing
+// it. It does nothing if the file was never written or is already deleted.
+func (f *QueryFile) SafeDelete() error {
+	if f.file == nil {
+		return nil
+	}
+	var err error
+	if f.inMemory {
+		// memory is not kept after the file is removed, one pass hides
+		// the content from anyone still holding the file open
+		err = shred(f.file, 1, PatternZeros)
+	} else {
+		err = f.client.overwrite(f.file)
+	}
+	if err != nil {
+		_ = f.remove()
+		return err
+	}
+	return f.remove()
+}
+
+// create creates the file with text in it, in memoryTempDir when the
+// client's SecureQueryFile is set and it can be used.
+func (f *QueryFile) create(text string) error {
+	c := f.client
+	pattern := c.tempFilePattern(".priq")
+	if c.SecureQueryFile {
+		if runtime.GOOS == "linux" {
+			file, err := c.createTempFileIn(memoryTempDir, pattern, text)
+			if err == nil {
+				f.file, f.inMemory = file, true
+				return nil
+			}
+			if c.Debug {
+				c.Logger.Debugf("cannot create query file in %s, using TempDir: %s", memoryTempDir, err)
+			}
+		} else if c.Debug {
+			c.Logger.Debugf("no memory backed temp dir on %s, using TempDir", runtime.GOOS)
+		}
+	}
+	file, err := c.createTempFile(pattern, text)
+	if err != nil {
+		return err
+	}
+	f.file = file
+	return nil
+}
+
+func (f *QueryFile) remove() error {
+	name := f.file.Name()
+	closeErr := f.file.Close()
+	f.file = nil
+	if err := f.client.removeFile(name); err != nil {
+		return err
+	}
+	if closeErr != nil {
+		return fileError("close query file", name, closeErr)
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package gopq
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ExecuteAndReadJSON runs the query with its output directed to a temp JSON
+// file, repairs the file and decodes it into dest.
+func (c *Client) ExecuteAndReadJSON(ctx context.Context, query PrimusQuery, timeout int, dest interface{}) error {
+	outputFilename, err := c.createTMPFile(c.tempFilePattern(".json"), "")
+	if err != nil {
+		return &QueryError{Op: "execute and read JSON", Err: err}
+	}
+	defer func() {
+		if FileExists(outputFilename) {
+			_ = c.safeDelete(outputFilename)
+		}
+	}()
+
+	query.Output = outputFilename
+	err = c.Execute(ctx, query, timeout)
+	if err != nil {
+		return &QueryError{Op: "execute and read JSON", Err: err}
+	}
+
+	info, err := os.Stat(outputFilename)
+	if err != nil {
+		return &JSONParseError{Path: outputFilename, Err: err}
+	}
+	if info.Size() == 0 {
+		// no records found
+		return nil
+	}
+	err = c.repairPrimusGeneratedJSON(outputFilename)
+	if err != nil {
+		return &JSONParseError{Path: outputFilename, Err: err}
+	}
+	jsonAsBytes, err := os.ReadFile(outputFilename)
+	if err != nil {
+		return &JSONParseError{Path: outputFilename, Err: err}
+	}
+	err = json.Unmarshal(jsonAsBytes, dest)
+	if err != nil {
+		return &JSONParseError{Path: outputFilename, Err: err}
+	}
+	return nil
+}
+
+func ExecuteAndReadJSON(ctx context.Context, query PrimusQuery, timeout int, dest interface{}) error {
+	return defaultClient().ExecuteAndReadJSON(ctx, query, timeout, dest)
+}
+
+// ExecuteWithOutput runs the query with its #OUTPUT directive set to
+// outputPath and returns the content primusquery wrote there. The file is
+// removed afterwards unless keepFile is set.
+func (c *Client) ExecuteWithOutput(ctx context.Context, query PrimusQuery, outputPath string, timeout int, keepFile bool) (string, error) {
+	query.Output = outputPath
+	if err := c.Execute(ctx, query, timeout); err != nil {
+		return "", err
+	}
+	content, err := os.ReadFile(outputPath)
+	if err != nil {
+		return "", fileError("read output", outputPath, err)
+	}
+	if !keepFile {
+		if err := c.safeDelete(outputPath); err != nil {
+			return string(content), err
+		}
+	}
+	return string(content), nil
+}
+
+func ExecuteWithOutput(ctx context.Context, query PrimusQuery, outputPath string, timeout int, keepFile bool) (string, error) {
+	return defaultClient().ExecuteWithOutput(ctx, query, outputPath, timeout, keepFile)
+}
+
+const utf8BOM = "\uFEFF"
+
+// ansiEscapePattern matches OSC, CSI and two-character escape sequences.
+var ansiEscapePattern = regexp.MustCompile(`\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b[0-Z\\-~]`)
+
+// SanitizeOutput removes ANSI escape sequences and a leading BOM from
+// primusquery output and turns Windows line endings into "\n".
+func SanitizeOutput(output string) string {
+	output = strings.TrimPrefix(output, utf8BOM)
+	output = ansiEscapePattern.ReplaceAllString(output, "")
+	return strings.ReplaceAll(output, "\r\n", "\n")
+}
+
+// ExecuteAndReadCSV runs the query and parses its output as CSV. A leading
+// BOM and Windows line endings are handled, and when the query has a Header
+// the first row is taken to be the header and dropped.
+func (c *Client) ExecuteAndReadCSV(ctx context.Context, query PrimusQuery, timeout int) ([][]string, error) {
+	output, err := c.ExecuteAndRead(ctx, query, timeout)
+	if err != nil {
+		return nil, err
+	}
+	records, err := parseCSVOutput(output)
+	if err != nil {
+		return nil, err
+	}
+	if query.Header != "" && len(records) > 0 {
+		records = records[1:]
+	}
+	return records, nil
+}
+
+func ExecuteAndReadCSV(ctx context.Context, query PrimusQuery, timeout int) ([][]string, error) {
+	return defaultClient().ExecuteAndReadCSV(ctx, query, timeout)
+}
+
+func parseCSVOutput(output string) ([][]string, error) {
+	output = strings.TrimPrefix(output, utf8BOM)
+	output = strings.ReplaceAll(output, "\r\n", "\n")
+
+	reader := csv.NewReader(strings.NewReader(output))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, &CSVParseError{Err: err}
+	}
+	return records, nil
+}
+
+// ExecuteAndReadLines runs the query and returns its output split into
+// lines. Unix and Windows line endings are accepted and empty lines at the
+// end of the output are dropped.
+func (c *Client) ExecuteAndReadLines(ctx context.Context, query PrimusQuery, timeout int) ([]string, error) {
+	output, err := c.ExecuteAndRead(ctx, query, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(output), nil
+}
+
+func ExecuteAndReadLines(ctx context.Context, query PrimusQuery, timeout int) ([]string, error) {
+	return defaultClient().ExecuteAndReadLines(ctx, query, timeout)
+}
+
+// CountQueryOutputLines returns the number of lines in output. A last line
+// without a line ending is counted too.
+func CountQueryOutputLines(output string) int {
+	n := strings.Count(output, "\n")
+	if output != "" && !strings.HasSuffix(output, "\n") {
+		n++
+	}
+	return n
+}
+
+func splitLines(output string) []string {
+	lines := strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n")
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// ExecuteAndReadMap runs the query, parses its output as in
+// ExecuteAndReadCSV and returns each record as a map from columnNames to
+// the field at the same position. Missing fields are empty strings and
+// fields beyond columnNames are dropped.
+func (c *Client) ExecuteAndReadMap(ctx context.Context, query PrimusQuery, timeout int, columnNames []string) ([]map[string]string, error) {
+	records, err := c.ExecuteAndReadCSV(ctx, query, timeout)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]map[string]string, 0, len(records))
+	for _, record := range records {
+		row := make(map[string]string, len(columnNames))
+		for i, name := range columnNames {
+			if i < len(record) {
+				row[name] = record[i]
+			} else {
+				row[name] = ""
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func ExecuteAndReadMap(ctx context.Context, query PrimusQuery, timeout int, columnNames []string) ([]map[string]string, error) {
+	return defaultClient().ExecuteAndReadMap(ctx, query, timeout, columnNames)
+}
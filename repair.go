@@ -0,0 +1,125 @@
+package gopq
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// RepairJSON reads JSON generated by primusquery from r and writes it to w,
+// repaired when it is truncated: everything after the last complete value
+// is dropped and open brackets and braces are closed. Valid JSON is written
+// unchanged. r is read to the end before anything is written.
+func RepairJSON(r io.Reader, w io.Writer) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if !json.Valid(data) {
+		data, err = repairJSON(data)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+type jsonFrame struct {
+	closer    byte
+	expectKey bool
+}
+
+// repairJSON truncates data after the last complete JSON value and closes
+// any brackets and braces still open at that point. An array element that
+// is cut short is dropped as a whole, so [{"a":1},{"b": becomes [{"a":1}].
+// Data that is invalid for any other reason than ending too early, or
+// than trailing commas and brackets, is not repaired.
+func repairJSON(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var (
+		stack   []jsonFrame
+		cut     int64 = -1
+		closers []byte
+	)
+	markCut := func() {
+		cut = dec.InputOffset()
+		closers = closers[:0]
+		for i := len(stack) - 1; i >= 0; i-- {
+			closers = append(closers, stack[i].closer)
+		}
+	}
+
+	var decErr error
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			decErr = err
+			break
+		}
+		switch t := token.(type) {
+		case json.Delim:
+			switch t {
+			case '[':
+				stack = append(stack, jsonFrame{closer: ']'})
+			case '{':
+				stack = append(stack, jsonFrame{closer: '}', expectKey: true})
+			default:
+				stack = stack[:len(stack)-1]
+				if len(stack) > 0 && stack[len(stack)-1].closer == '}' {
+					stack[len(stack)-1].expectKey = true
+				}
+			}
+		default:
+			if len(stack) > 0 {
+				top := &stack[len(stack)-1]
+				if top.closer == '}' {
+					top.expectKey = !top.expectKey
+					if !top.expectKey {
+						// an object key, the value is still missing
+						continue
+					}
+				}
+			}
+		}
+		if completeElements(stack) {
+			markCut()
+		}
+		if len(stack) == 0 {
+			break
+		}
+	}
+
+	if cut < 0 {
+		return nil, ErrUnrepairableJSON
+	}
+	// only a cut-short tail is repaired; a broken value before it would
+	// otherwise silently drop every record after it
+	truncated := errors.Is(decErr, io.EOF) || errors.Is(decErr, io.ErrUnexpectedEOF)
+	if !truncated && len(bytes.Trim(data[cut:], " \t\r\n,]}")) > 0 {
+		return nil, ErrUnrepairableJSON
+	}
+	repaired := make([]byte, 0, int(cut)+len(closers)+1)
+	repaired = append(repaired, bytes.TrimRight(data[:cut], " \t\r\n")...)
+	if len(closers) > 0 {
+		repaired = append(repaired, '\n')
+		repaired = append(repaired, closers...)
+	}
+	if !json.Valid(repaired) {
+		return nil, ErrUnrepairableJSON
+	}
+	return repaired, nil
+}
+
+// completeElements reports whether cutting at the current position leaves
+// only whole elements in every open array, i.e. no array is waiting for an
+// element that has been started but not finished.
+func completeElements(stack []jsonFrame) bool {
+	for i := 0; i < len(stack)-1; i++ {
+		if stack[i].closer == ']' {
+			return false
+		}
+	}
+	return true
+}
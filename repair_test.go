@@ -0,0 +1,83 @@
+package gopq
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRepairJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"valid", `[{"a":1}]`, `[{"a":1}]`},
+		{"open array", `[`, "[\n]"},
+		{"truncated element", `[{"a":1},{"d":`, "[{\"a\":1}\n]"},
+		{"truncated element value", `[{"a":1},{"d":2`, "[{\"a\":1}\n]"},
+		{"truncated nested array", `[{"a":1},[1,2`, "[{\"a\":1}\n]"},
+		{"truncated key", `{"a":1,"b`, "{\"a\":1\n}"},
+		{"missing value", `{"a":1,"b":`, "{\"a\":1\n}"},
+		{"array in object", `{"a":[1,2`, "{\"a\":[1,2\n]}"},
+		{"trailing comma", `[1,2,`, "[1,2\n]"},
+		{"trailing comma before bracket", "[{\"a\":1},\r\n]", "[{\"a\":1}\n]"},
+		{"truncated string", `{"a":"bc`, "{\n}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := RepairJSON(strings.NewReader(tt.input), &out); err != nil {
+				t.Fatalf("RepairJSON(%q): %v", tt.input, err)
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("RepairJSON(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepairJSONUnrepairable(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"not json", `not json`},
+		{"unescaped quote", "[{\"a\":1},{\"a\":\"b\"c\"},{\"a\":2},\r\n]"},
+		{"unescaped quote truncated", `[{"a":1},{"a":"b"c"},{"a":2`},
+		{"missing comma", `[{"a":1}{"a":2}]`},
+		{"trailing garbage", `[{"a":1}] x`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := RepairJSON(strings.NewReader(tt.input), &out)
+			if !errors.Is(err, ErrUnrepairableJSON) {
+				t.Fatalf("RepairJSON(%q) error = %v, want ErrUnrepairableJSON", tt.input, err)
+			}
+			if out.Len() != 0 {
+				t.Errorf("RepairJSON(%q) wrote %q", tt.input, out.String())
+			}
+		})
+	}
+}
+
+func TestRepairPrimusGeneratedJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.json")
+	if err := os.WriteFile(path, []byte(`[{"a":1},{"d":`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := RepairPrimusGeneratedJSON(path); err != nil {
+		t.Fatalf("RepairPrimusGeneratedJSON: %v", err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[{\"a\":1}\n]"; string(got) != want {
+		t.Errorf("repaired file = %q, want %q", got, want)
+	}
+}
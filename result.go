@@ -0,0 +1,96 @@
+package gopq
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+)
+
+// ExecutionResult holds both output streams of a primusquery run and the
+// host that produced them.
+type ExecutionResult struct {
+	Output string
+	Stderr string
+	Host   string
+}
+
+// ExecuteAndCapture works like ExecuteAndRead but also returns primusquery's
+// stderr, which often holds the useful message on authentication failures.
+func (c *Client) ExecuteAndCapture(ctx context.Context, query PrimusQuery, timeout int) (ExecutionResult, error) {
+	key := cacheKey(query)
+	if result, ok := c.cachedResult(ctx, key); ok {
+		return result, nil
+	}
+	var result ExecutionResult
+	_, err := c.withFailover(ctx, query, func(q PrimusQuery) error {
+		var err error
+		result, err = c.executeAndReadWithRetry(ctx, q, timeout)
+		return err
+	})
+	if err == nil {
+		c.storeResult(key, result)
+	}
+	return result, err
+}
+
+func ExecuteAndCapture(ctx context.Context, query PrimusQuery, timeout int) (ExecutionResult, error) {
+	return defaultClient().ExecuteAndCapture(ctx, query, timeout)
+}
+
+// QueryResult is the parsed output of a read query.
+type QueryResult struct {
+	RawOutput string
+	Stderr    string
+	Stats     QueryStats
+	Records   []string
+}
+
+func newQueryResult(output string) (*QueryResult, error) {
+	stats, err := ParseQueryStats(output)
+	if err != nil {
+		return nil, err
+	}
+	var records []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line != "" {
+			records = append(records, line)
+		}
+	}
+	return &QueryResult{RawOutput: output, Stats: stats, Records: records}, nil
+}
+
+// AsJSON returns the raw output as JSON, repairing the trailing garbage
+// primusquery leaves in generated JSON.
+func (r *QueryResult) AsJSON() ([]byte, error) {
+	raw := []byte(r.RawOutput)
+	if json.Valid(raw) {
+		return raw, nil
+	}
+	return repairJSON(raw)
+}
+
+// AsCSV parses the raw output as comma separated values.
+func (r *QueryResult) AsCSV() ([][]string, error) {
+	reader := csv.NewReader(strings.NewReader(r.RawOutput))
+	reader.FieldsPerRecord = -1
+	return reader.ReadAll()
+}
+
+func (c *Client) ExecuteAndReadResult(ctx context.Context, query PrimusQuery, timeout int) (*QueryResult, error) {
+	execution, err := c.ExecuteAndCapture(ctx, query, timeout)
+	if err != nil {
+		return nil, err
+	}
+	result, err := newQueryResult(execution.Output)
+	if err != nil {
+		return nil, err
+	}
+	result.Stderr = execution.Stderr
+	return result, nil
+}
+
+func ExecuteAndReadResult(ctx context.Context, query PrimusQuery, timeout int) (*QueryResult, error) {
+	return defaultClient().ExecuteAndReadResult(ctx, query, timeout)
+}
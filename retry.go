@@ -0,0 +1,95 @@
+package gopq
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// RetryPolicy controls how Execute and ExecuteAndRead retry timeouts and
+// non-zero primusquery exits. A zero policy runs each query once.
+type RetryPolicy struct {
+	MaxAttempts  int
+	InitialDelay time.Duration
+	Multiplier   float64
+}
+
+func WithRetry(maxAttempts int, initialDelay time.Duration, multiplier float64) Option {
+	return func(c *Client) {
+		c.RetryPolicy = RetryPolicy{
+			MaxAttempts:  maxAttempts,
+			InitialDelay: initialDelay,
+			Multiplier:   multiplier,
+		}
+	}
+}
+
+func isRetryable(err error) bool {
+	var pe *PrimusError
+	return errors.Is(err, ErrQueryTimeout) || errors.As(err, &pe)
+}
+
+func (c *Client) withRetry(ctx context.Context, op func() error) error {
+	return c.retry(ctx, c.RetryPolicy, isRetryable, op)
+}
+
+// retry runs op until it succeeds, policy.MaxAttempts is reached or
+// retryable rejects the error, backing off between attempts as in policy.
+func (c *Client) retry(ctx context.Context, policy RetryPolicy, retryable func(error) bool, op func() error) error {
+	multiplier := policy.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+	delay := policy.InitialDelay
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = op()
+		if err == nil || attempt >= policy.MaxAttempts || !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+		if c.Debug {
+			c.Logger.Debugf("attempt %d failed, retrying in %s: %s", attempt, delay, err)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		delay = time.Duration(float64(delay) * multiplier)
+	}
+}
+
+// DefaultRetryDelay is the delay before the first retry of
+// ExecuteAndReadWithRetry when the client's RetryPolicy has no InitialDelay.
+const DefaultRetryDelay = time.Second
+
+// ExecuteAndReadWithRetry runs the query like ExecuteAndRead, making up to
+// maxAttempts attempts while retryOn accepts the error; a nil retryOn
+// retries timeouts and primusquery failures. The client's RetryPolicy
+// delays are used between attempts, starting from DefaultRetryDelay when
+// InitialDelay is zero, and ctx bounds all attempts together.
+func (c *Client) ExecuteAndReadWithRetry(ctx context.Context, query PrimusQuery, timeout, maxAttempts int, retryOn func(error) bool) (string, error) {
+	if retryOn == nil {
+		retryOn = isRetryable
+	}
+	policy := c.RetryPolicy
+	policy.MaxAttempts = maxAttempts
+	if policy.InitialDelay <= 0 {
+		policy.InitialDelay = DefaultRetryDelay
+	}
+	var result ExecutionResult
+	_, err := c.withFailover(ctx, query, func(q PrimusQuery) error {
+		return c.retry(ctx, policy, retryOn, func() error {
+			var err error
+			result, err = c.executeAndRead(ctx, q, timeout)
+			return err
+		})
+	})
+	return result.Output, err
+}
+
+func ExecuteAndReadWithRetry(ctx context.Context, query PrimusQuery, timeout, maxAttempts int, retryOn func(error) bool) (string, error) {
+	return defaultClient().ExecuteAndReadWithRetry(ctx, query, timeout, maxAttempts, retryOn)
+}
@@ -0,0 +1,13 @@
+package gopq
+
+import "context"
+
+// QueryRunner is the part of Client most application code needs. Code
+// written against it can be given a Client, a MultiHostClient or a test
+// double.
+type QueryRunner interface {
+	Execute(ctx context.Context, query PrimusQuery, timeout int) error
+	ExecuteAndRead(ctx context.Context, query PrimusQuery, timeout int) (string, error)
+}
+
+var _ QueryRunner = (*Client)(nil)
@@ -0,0 +1,55 @@
+package gopq
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const searchSpecialChars = " \t\r\n\"'\\()=<>!&|"
+
+// BuildSearch joins key=value conditions with AND or OR. Keys must be
+// column names such as V1, as in a sort, and are sorted so the result is
+// stable. Values containing spaces or special characters are quoted.
+func BuildSearch(conditions map[string]string, operator string) (string, error) {
+	op := strings.ToUpper(strings.TrimSpace(operator))
+	if op != "AND" && op != "OR" {
+		return "", fmt.Errorf("%w: unsupported search operator %q", ErrInvalidQuery, operator)
+	}
+
+	keys := make([]string, 0, len(conditions))
+	for key := range conditions {
+		if !sortColumnPattern.MatchString(key) {
+			return "", fmt.Errorf("%w: invalid search key %q", ErrInvalidQuery, key)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value := conditions[key]
+		if value == "" || strings.ContainsAny(value, searchSpecialChars) {
+			value = QuoteSearchValue(value)
+		}
+		parts = append(parts, key+"="+value)
+	}
+	return strings.Join(parts, " "+op+" "), nil
+}
+
+var searchValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\r", `\r`, "\n", `\n`)
+
+// EscapeSearchValue escapes the backslashes and double quotes in value so
+// it cannot end a quoted search value early, and writes CR and LF as \r
+// and \n so it cannot start a new directive line in the query file.
+// Parentheses, operators and spaces are only inert inside quotes, so
+// untrusted input should be passed through QuoteSearchValue.
+func EscapeSearchValue(value string) string {
+	return searchValueEscaper.Replace(value)
+}
+
+// QuoteSearchValue returns value escaped and in double quotes, safe to use
+// as the value of a search condition, e.g. "V1=" + QuoteSearchValue(name).
+func QuoteSearchValue(value string) string {
+	return `"` + EscapeSearchValue(value) + `"`
+}
@@ -0,0 +1,93 @@
+package gopq
+
+import (
+	cryptorand "crypto/rand"
+	"fmt"
+	"os"
+)
+
+// ShredPattern is what ShredFile writes over a file on each pass.
+type ShredPattern int
+
+const (
+	// PatternZeros writes zero bytes on every pass.
+	PatternZeros ShredPattern = iota
+	// PatternOnes writes 0xFF bytes on every pass.
+	PatternOnes
+	// PatternRandom writes random bytes from crypto/rand on every pass.
+	PatternRandom
+	// PatternDoDM cycles through zeros, ones and random bytes as in
+	// DoD 5220.22-M. It is what SafeDelete uses.
+	PatternDoDM
+)
+
+// ShredFile overwrites the content of path passes times with pattern,
+// syncing after each pass, and then removes it. A passes of zero or less
+// uses DefaultSafeDeletePasses. Overwriting is not a guarantee on
+// journaling or copy-on-write filesystems and SSDs.
+func ShredFile(path string, passes int, pattern ShredPattern) error {
+	if pattern < PatternZeros || pattern > PatternDoDM {
+		return fmt.Errorf("shred %s: unknown pattern %d", path, pattern)
+	}
+	if passes <= 0 {
+		passes = DefaultSafeDeletePasses
+	}
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return fileError("shred", path, err)
+	}
+	err = shred(file, passes, pattern)
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fileError("shred", path, closeErr)
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return fileError("shred", path, err)
+	}
+	return nil
+}
+
+// shred writes over the whole content of file passes times with pattern.
+func shred(file *os.File, passes int, pattern ShredPattern) error {
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return fileError("shred", file.Name(), err)
+	}
+	buf := make([]byte, fileInfo.Size())
+	for i := 0; i < passes; i++ {
+		err := fillShredPattern(buf, pattern, i)
+		if err == nil {
+			_, err = file.WriteAt(buf, 0)
+		}
+		if err == nil {
+			err = file.Sync()
+		}
+		if err != nil {
+			return fileError("shred", file.Name(), err)
+		}
+	}
+	return nil
+}
+
+// fillShredPattern fills b with what pattern writes on the given pass.
+func fillShredPattern(b []byte, pattern ShredPattern, pass int) error {
+	if pattern == PatternDoDM {
+		pattern = ShredPattern(pass % 3)
+	}
+	switch pattern {
+	case PatternZeros:
+		for i := range b {
+			b[i] = 0x00
+		}
+	case PatternOnes:
+		for i := range b {
+			b[i] = 0xFF
+		}
+	default:
+		_, err := cryptorand.Read(b)
+		return err
+	}
+	return nil
+}
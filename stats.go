@@ -0,0 +1,58 @@
+package gopq
+
+import (
+	"regexp"
+	"strconv"
+)
+
+type QueryStats struct {
+	ErrorCount   int
+	WarningCount int
+	RecordsFound int
+	ProcessingMs int
+}
+
+var (
+	errorCountPattern   = regexp.MustCompile(`Errors: ([0-9]+)`)
+	warningCountPattern = regexp.MustCompile(`Warnings: ([0-9]+)`)
+	recordsFoundPattern = regexp.MustCompile(`(?:Found|Records): ([0-9]+)`)
+	processingPattern   = regexp.MustCompile(`Time: ([0-9]+) ?ms`)
+)
+
+// ParseQueryStats collects the statistics primusquery prints after a run.
+// Like CountPQErrors and CountPQWarnings, a statistic printed more than once
+// is summed over all its lines. Statistics missing from the output are left
+// at zero.
+func ParseQueryStats(output string) (QueryStats, error) {
+	var stats QueryStats
+	fields := []struct {
+		pattern *regexp.Regexp
+		value   *int
+	}{
+		{errorCountPattern, &stats.ErrorCount},
+		{warningCountPattern, &stats.WarningCount},
+		{recordsFoundPattern, &stats.RecordsFound},
+		{processingPattern, &stats.ProcessingMs},
+	}
+	for _, field := range fields {
+		n, err := sumMatches(field.pattern, output)
+		if err != nil {
+			return QueryStats{}, err
+		}
+		*field.value = n
+	}
+	return stats, nil
+}
+
+// sumMatches adds up the number captured by every match of pattern.
+func sumMatches(pattern *regexp.Regexp, output string) (int, error) {
+	sum := 0
+	for _, match := range pattern.FindAllStringSubmatch(output, -1) {
+		n, err := strconv.Atoi(match[1])
+		if err != nil {
+			return 0, err
+		}
+		sum += n
+	}
+	return sum, nil
+}
@@ -0,0 +1,71 @@
+package gopq
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func readFixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "stats", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestParseQueryStats(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    QueryStats
+	}{
+		{"query.txt", QueryStats{RecordsFound: 2, ProcessingMs: 35}},
+		{"import.txt", QueryStats{ErrorCount: 3, WarningCount: 4, RecordsFound: 3, ProcessingMs: 120}},
+		{"nostats.txt", QueryStats{}},
+		{"crlf.txt", QueryStats{WarningCount: 2, RecordsFound: 7, ProcessingMs: 8}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			got, err := ParseQueryStats(readFixture(t, tt.fixture))
+			if err != nil {
+				t.Fatalf("ParseQueryStats: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseQueryStats() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountPQErrorsAndWarnings(t *testing.T) {
+	output := readFixture(t, "import.txt")
+	if n, err := CountPQErrors(output); err != nil || n != 3 {
+		t.Errorf("CountPQErrors() = %d, %v, want 3", n, err)
+	}
+	if n, err := CountPQWarnings(output); err != nil || n != 4 {
+		t.Errorf("CountPQWarnings() = %d, %v, want 4", n, err)
+	}
+}
+
+func TestParseNewCardIDs(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    []int
+	}{
+		{"import.txt", []int{10452, 10453, 10454}},
+		{"query.txt", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			got, err := ParseNewCardIDs(readFixture(t, tt.fixture))
+			if err != nil {
+				t.Fatalf("ParseNewCardIDs: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseNewCardIDs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
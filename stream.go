@@ -0,0 +1,65 @@
+package gopq
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+)
+
+// ExecuteAndStream runs the query and copies primusquery's stdout to w as it
+// is produced instead of collecting it in memory.
+func (c *Client) ExecuteAndStream(ctx context.Context, query PrimusQuery, timeout int, w io.Writer) error {
+	if err := c.prepare(ctx, query); err != nil {
+		return err
+	}
+	query.Output = ""
+	_, err := c.runQuery(ctx, "execute and stream", query, timeout, w)
+	return err
+}
+
+func ExecuteAndStream(ctx context.Context, query PrimusQuery, timeout int, w io.Writer) error {
+	return defaultClient().ExecuteAndStream(ctx, query, timeout, w)
+}
+
+// ExecuteToFile runs the query and writes primusquery's stdout straight to
+// destPath. A *DestinationError is returned when destPath cannot be written.
+func (c *Client) ExecuteToFile(ctx context.Context, query PrimusQuery, timeout int, destPath string) error {
+	if err := c.prepare(ctx, query); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return &DestinationError{Path: destPath, Err: err}
+	}
+	query.Output = ""
+	_, err = c.runQuery(ctx, "execute to file", query, timeout, f)
+	if closeErr := f.Close(); closeErr != nil && err == nil {
+		err = &DestinationError{Path: destPath, Err: closeErr}
+	}
+	return err
+}
+
+func ExecuteToFile(ctx context.Context, query PrimusQuery, timeout int, destPath string) error {
+	return defaultClient().ExecuteToFile(ctx, query, timeout, destPath)
+}
+
+// ExecuteAndReadGzipped runs the query and returns its output compressed
+// with gzip. The output is compressed as it is produced, so no uncompressed
+// copy is kept in memory.
+func (c *Client) ExecuteAndReadGzipped(ctx context.Context, query PrimusQuery, timeout int) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := c.ExecuteAndStream(ctx, query, timeout, zw); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func ExecuteAndReadGzipped(ctx context.Context, query PrimusQuery, timeout int) ([]byte, error) {
+	return defaultClient().ExecuteAndReadGzipped(ctx, query, timeout)
+}
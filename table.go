@@ -0,0 +1,65 @@
+package gopq
+
+import "context"
+
+// Table is tabular query output. Header holds the column names when the
+// output had a header row.
+type Table struct {
+	Header []string
+	Rows   [][]string
+}
+
+// RowCount returns the number of rows, not counting the header.
+func (t *Table) RowCount() int {
+	return len(t.Rows)
+}
+
+// Column returns the values of the named column, nil when the table has no
+// such column. Rows too short for the column get an empty value.
+func (t *Table) Column(name string) []string {
+	col := -1
+	for i, h := range t.Header {
+		if h == name {
+			col = i
+			break
+		}
+	}
+	if col < 0 {
+		return nil
+	}
+	values := make([]string, len(t.Rows))
+	for i := range t.Rows {
+		values[i] = t.Cell(i, col)
+	}
+	return values
+}
+
+// Cell returns the field at row and col, empty when it is out of range.
+func (t *Table) Cell(row, col int) string {
+	if row < 0 || row >= len(t.Rows) || col < 0 || col >= len(t.Rows[row]) {
+		return ""
+	}
+	return t.Rows[row][col]
+}
+
+// ExecuteAndReadTable runs the query and parses its output as CSV into a
+// Table. With hasHeader the first row becomes the Header.
+func (c *Client) ExecuteAndReadTable(ctx context.Context, query PrimusQuery, timeout int, hasHeader bool) (*Table, error) {
+	output, err := c.ExecuteAndRead(ctx, query, timeout)
+	if err != nil {
+		return nil, err
+	}
+	records, err := parseCSVOutput(output)
+	if err != nil {
+		return nil, err
+	}
+	table := &Table{Rows: records}
+	if hasHeader && len(records) > 0 {
+		table.Header, table.Rows = records[0], records[1:]
+	}
+	return table, nil
+}
+
+func ExecuteAndReadTable(ctx context.Context, query PrimusQuery, timeout int, hasHeader bool) (*Table, error) {
+	return defaultClient().ExecuteAndReadTable(ctx, query, timeout, hasHeader)
+}
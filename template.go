@@ -0,0 +1,64 @@
+package gopq
+
+import (
+	"strings"
+	"text/template"
+)
+
+// QueryTemplate is a PrimusQuery whose fields may contain text/template
+// expressions, e.g. Search: "V1={{.LastName}} AND V2={{.FirstName}}". The
+// password is never treated as a template.
+type QueryTemplate struct {
+	base      PrimusQuery
+	templates map[string]*template.Template
+}
+
+// templateFields returns pointers to the query fields that are rendered as
+// templates.
+func templateFields(q *PrimusQuery) map[string]*string {
+	return map[string]*string{
+		"Host":     &q.Host,
+		"Port":     &q.Port,
+		"User":     &q.User,
+		"Output":   &q.Output,
+		"Database": &q.Database,
+		"Search":   &q.Search,
+		"Sort":     &q.Sort,
+		"Header":   &q.Header,
+		"Data":     &q.Data,
+		"Footer":   &q.Footer,
+	}
+}
+
+// NewQueryTemplate parses the template expressions in the fields of base.
+func NewQueryTemplate(base PrimusQuery) (*QueryTemplate, error) {
+	t := &QueryTemplate{base: base, templates: map[string]*template.Template{}}
+	for name, value := range templateFields(&base) {
+		if !strings.Contains(*value, "{{") {
+			continue
+		}
+		tmpl, err := template.New(name).Option("missingkey=error").Parse(*value)
+		if err != nil {
+			return nil, err
+		}
+		t.templates[name] = tmpl
+	}
+	return t, nil
+}
+
+// Render fills the templates with data and validates the resulting query.
+func (t *QueryTemplate) Render(data interface{}) (PrimusQuery, error) {
+	query := t.base
+	fields := templateFields(&query)
+	for name, tmpl := range t.templates {
+		var sb strings.Builder
+		if err := tmpl.Execute(&sb, data); err != nil {
+			return PrimusQuery{}, err
+		}
+		*fields[name] = sb.String()
+	}
+	if err := query.Validate(); err != nil {
+		return PrimusQuery{}, err
+	}
+	return query, nil
+}
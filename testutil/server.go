@@ -0,0 +1,106 @@
+// Package testutil provides helpers for testing code that runs primusquery
+// through gopq.
+package testutil
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeSource is the program built by StartTestServer. It sends the path of
+// its query or import file to the test process and prints the answer.
+const fakeSource = `package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+)
+
+var addr string
+
+func main() {
+	args := os.Args[1:]
+	switch {
+	case len(args) == 1 && (args[0] == "--version" || args[0] == "-version"):
+		fmt.Println("primusquery 0.0.0-testutil")
+		return
+	case len(args) == 3 && args[2] == "-update":
+		fmt.Println("updated")
+		return
+	case len(args) == 0:
+		fmt.Fprintln(os.Stderr, "usage: primusquery <query-file>")
+		os.Exit(2)
+	}
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+	fmt.Fprintln(conn, args[len(args)-1])
+	if _, err := io.Copy(os.Stdout, conn); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+`
+
+// StartTestServer builds a fake primusquery binary and returns its path for
+// use as PrimusQueryPath. For every query the binary passes the path of
+// the query file to handler, which runs in the test process, and prints
+// what handler returns. Imports pass the import file the same way; -update
+// and --version succeed without calling handler. Building the binary needs
+// the go command. Everything is cleaned up when the test ends.
+func StartTestServer(t testing.TB, handler func(queryFile string) string) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("testutil: listen: %s", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go serve(listener, handler)
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(src, []byte(fakeSource), 0600); err != nil {
+		t.Fatalf("testutil: write fake primusquery source: %s", err)
+	}
+	binary := filepath.Join(dir, "primusquery")
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+	cmd := exec.Command("go", "build", "-o", binary, "-ldflags", "-X main.addr="+listener.Addr().String(), src)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GO111MODULE=off")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("testutil: build fake primusquery: %s\n%s", err, out)
+	}
+	return binary
+}
+
+func serve(listener net.Listener, handler func(queryFile string) string) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			path, err := bufio.NewReader(conn).ReadString('\n')
+			if err != nil && err != io.EOF {
+				return
+			}
+			_, _ = io.WriteString(conn, handler(strings.TrimSuffix(path, "\n")))
+		}()
+	}
+}
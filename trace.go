@@ -0,0 +1,57 @@
+package gopq
+
+import (
+	"context"
+	"regexp"
+)
+
+// Tracer starts spans around Execute and ExecuteAndRead. It lets callers
+// plug in OpenTelemetry or another tracing library without gopq depending
+// on it; an OpenTelemetry adapter starts an otel span with the name and
+// attributes and ends it with the error recorded.
+type Tracer interface {
+	Start(ctx context.Context, name string, attributes map[string]string) (context.Context, Span)
+}
+
+// Span is a span started by a Tracer.
+type Span interface {
+	End(err error)
+}
+
+// WithTracer sets the client's Tracer.
+func WithTracer(t Tracer) Option {
+	return func(c *Client) {
+		c.tracer = t
+	}
+}
+
+// SetTracer sets the Tracer used by the package-level functions.
+func SetTracer(t Tracer) {
+	stdMu.Lock()
+	defer stdMu.Unlock()
+	std.tracer = t
+}
+
+type noopSpan struct{}
+
+func (noopSpan) End(error) {}
+
+// startSpan starts a span for query with the client's Tracer, if any. The
+// search is recorded with its values masked.
+func (c *Client) startSpan(ctx context.Context, name string, query PrimusQuery) (context.Context, Span) {
+	if c.tracer == nil {
+		return ctx, noopSpan{}
+	}
+	return c.tracer.Start(ctx, name, map[string]string{
+		"primus.host":     query.Host,
+		"primus.database": query.Database,
+		"primus.search":   redactSearch(query.Search),
+	})
+}
+
+var searchValuePattern = regexp.MustCompile(`(=\s*)("(?:[^"\\]|\\.)*"|[^\s()]+)`)
+
+// redactSearch replaces the values of the search conditions with "?".
+func redactSearch(search string) string {
+	return searchValuePattern.ReplaceAllString(search, "${1}?")
+}
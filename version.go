@@ -0,0 +1,42 @@
+package gopq
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+var versionPattern = regexp.MustCompile(`[0-9]+\.[0-9]+(\.[0-9]+)?([-+][0-9A-Za-z.-]+)?`)
+
+// GetPrimusVersion runs the primusquery binary with --version. It returns
+// the version number when one is found, otherwise the first output line.
+func (c *Client) GetPrimusVersion(ctx context.Context) (string, error) {
+	var out bytes.Buffer
+	stderr, err := c.exec().Version(ctx, c.PrimusQueryPath, &out)
+	if errors.Is(err, ErrBinaryNotFound) {
+		return "", err
+	}
+	if ctx.Err() != nil {
+		return "", newContextError("version", ErrQueryTimeout, ctx.Err())
+	}
+	if err != nil {
+		pe := newPrimusError("version", err)
+		pe.Stderr = stderr
+		return "", pe
+	}
+	if c.Debug {
+		c.Logger.Debugf("version output: %s", out.String())
+	}
+
+	output := strings.TrimSpace(out.String())
+	if version := versionPattern.FindString(output); version != "" {
+		return version, nil
+	}
+	return strings.TrimSpace(strings.SplitN(output, "\n", 2)[0]), nil
+}
+
+func GetPrimusVersion(ctx context.Context) (string, error) {
+	return defaultClient().GetPrimusVersion(ctx)
+}
@@ -0,0 +1,73 @@
+package gopq
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// watchPollInterval is how often WatchPrimusOutput checks for new lines.
+const watchPollInterval = 200 * time.Millisecond
+
+// WatchPrimusOutput follows an output file that primusquery is still
+// writing and calls lineHandler for each complete line. It returns the
+// context error when ctx is done or the first error from lineHandler.
+func WatchPrimusOutput(ctx context.Context, path string, lineHandler func(line string) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fileError("watch output", path, err)
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	var partial strings.Builder
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		chunk, err := reader.ReadString('\n')
+		partial.WriteString(chunk)
+		if err == nil {
+			line := strings.TrimSuffix(strings.TrimSuffix(partial.String(), "\n"), "\r")
+			partial.Reset()
+			if err := lineHandler(line); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != io.EOF {
+			return fileError("watch output", path, err)
+		}
+
+		timer := time.NewTimer(watchPollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// WaitForFile polls every pollInterval until path exists and returns the
+// context error if ctx is done first. A pollInterval of zero or less uses
+// the WatchPrimusOutput interval.
+func WaitForFile(ctx context.Context, path string, pollInterval time.Duration) error {
+	if pollInterval <= 0 {
+		pollInterval = watchPollInterval
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for !FileExists(path) {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("wait for %s: %w", path, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
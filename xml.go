@@ -0,0 +1,90 @@
+package gopq
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// ExecuteAndReadXML runs the query with its output directed to a temp XML
+// file and decodes the file into dest with encoding/xml. Output with and
+// without an XML declaration is accepted, and a declared ISO-8859-1 encoding
+// is converted. Decoding failures are returned as *XMLParseError.
+func (c *Client) ExecuteAndReadXML(ctx context.Context, query PrimusQuery, timeout int, dest interface{}) error {
+	outputFilename, err := c.createTMPFile(c.tempFilePattern(".xml"), "")
+	if err != nil {
+		return &QueryError{Op: "execute and read XML", Err: err}
+	}
+	defer func() {
+		if FileExists(outputFilename) {
+			_ = c.safeDelete(outputFilename)
+		}
+	}()
+
+	query.Output = outputFilename
+	err = c.Execute(ctx, query, timeout)
+	if err != nil {
+		return &QueryError{Op: "execute and read XML", Err: err}
+	}
+
+	data, err := os.ReadFile(outputFilename)
+	if err != nil {
+		return &XMLParseError{Path: outputFilename, Err: err}
+	}
+	data = bytes.TrimPrefix(data, []byte(utf8BOM))
+	if len(bytes.TrimSpace(data)) == 0 {
+		// no records found
+		return nil
+	}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.CharsetReader = xmlCharsetReader
+	if err := decoder.Decode(dest); err != nil {
+		return &XMLParseError{Path: outputFilename, Err: err}
+	}
+	return nil
+}
+
+func ExecuteAndReadXML(ctx context.Context, query PrimusQuery, timeout int, dest interface{}) error {
+	return defaultClient().ExecuteAndReadXML(ctx, query, timeout, dest)
+}
+
+// xmlCharsetReader converts the encodings Primus declares in its XML
+// output to UTF-8.
+func xmlCharsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "utf-8", "utf8", "us-ascii":
+		return input, nil
+	case "iso-8859-1", "latin1":
+		return latin1Reader{r: bufio.NewReader(input)}, nil
+	}
+	return nil, fmt.Errorf("unsupported XML encoding %q", charset)
+}
+
+// latin1Reader decodes ISO-8859-1, where every byte is the code point of
+// the same value.
+type latin1Reader struct {
+	r *bufio.Reader
+}
+
+func (l latin1Reader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		b, err := l.r.ReadByte()
+		if err != nil {
+			return n, err
+		}
+		if b >= utf8.RuneSelf && n+2 > len(p) {
+			// no room for the two byte encoding, keep it for the next Read
+			_ = l.r.UnreadByte()
+			break
+		}
+		n += utf8.EncodeRune(p[n:], rune(b))
+	}
+	return n, nil
+}